@@ -20,12 +20,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"text/template"
 	"time"
 
 	"github.com/cooleo/goweb/grace"
 	"github.com/cooleo/goweb/toolbox"
-	"github.com/cooleo/goweb/utils"
 )
 
 // BeeAdminApp is the default adminApp used by admin module.
@@ -95,12 +95,18 @@ func listConf(rw http.ResponseWriter, r *http.Request) {
 		m["BConfig.AppName"] = BConfig.AppName
 		m["BConfig.RunMode"] = BConfig.RunMode
 		m["BConfig.RouterCaseSensitive"] = BConfig.RouterCaseSensitive
+		m["BConfig.RouterRedirectCase"] = BConfig.RouterRedirectCase
+		m["BConfig.RouterCleanPath"] = BConfig.RouterCleanPath
+		m["BConfig.RouterTrailingSlash"] = BConfig.RouterTrailingSlash
+		m["BConfig.RouterRedirectCode"] = BConfig.RouterRedirectCode
 		m["BConfig.ServerName"] = BConfig.ServerName
 		m["BConfig.RecoverPanic"] = BConfig.RecoverPanic
 		m["BConfig.CopyRequestBody"] = BConfig.CopyRequestBody
 		m["BConfig.EnableGzip"] = BConfig.EnableGzip
 		m["BConfig.MaxMemory"] = BConfig.MaxMemory
 		m["BConfig.EnableErrorsShow"] = BConfig.EnableErrorsShow
+		m["BConfig.ErrorMode"] = BConfig.ErrorMode
+		m["BConfig.AlwaysRunAfterFilters"] = BConfig.AlwaysRunAfterFilters
 		m["BConfig.Listen.Graceful"] = BConfig.Listen.Graceful
 		m["BConfig.Listen.ServerTimeOut"] = BConfig.Listen.ServerTimeOut
 		m["BConfig.Listen.ListenTCP4"] = BConfig.Listen.ListenTCP4
@@ -127,6 +133,7 @@ func listConf(rw http.ResponseWriter, r *http.Request) {
 		m["BConfig.WebConfig.TemplateLeft"] = BConfig.WebConfig.TemplateLeft
 		m["BConfig.WebConfig.TemplateRight"] = BConfig.WebConfig.TemplateRight
 		m["BConfig.WebConfig.ViewsPath"] = BConfig.WebConfig.ViewsPath
+		m["BConfig.WebConfig.EnableLiveReload"] = BConfig.WebConfig.EnableLiveReload
 		m["BConfig.WebConfig.EnableXSRF"] = BConfig.WebConfig.EnableXSRF
 		m["BConfig.WebConfig.XSRFKEY"] = BConfig.WebConfig.XSRFKey
 		m["BConfig.WebConfig.XSRFExpire"] = BConfig.WebConfig.XSRFExpire
@@ -161,7 +168,7 @@ func listConf(rw http.ResponseWriter, r *http.Request) {
 			methods     = []string{}
 			methodsData = make(map[string]interface{})
 		)
-		for method, t := range BeeApp.Handlers.routers {
+		for method, t := range BeeApp.Handlers.load().routers {
 
 			resultList := new([][]string)
 
@@ -180,35 +187,31 @@ func listConf(rw http.ResponseWriter, r *http.Request) {
 		var (
 			content = map[string]interface{}{
 				"Fields": []string{
+					"Name",
 					"Router Pattern",
-					"Filter Function",
+					"Priority",
+					"Return On Output",
 				},
 			}
 			filterTypes    = []string{}
 			filterTypeData = make(map[string]interface{})
 		)
 
-		if BeeApp.Handlers.enableFilter {
-			var filterType string
-			for k, fr := range map[int]string{
-				BeforeStatic: "Before Static",
-				BeforeRouter: "Before Router",
-				BeforeExec:   "Before Exec",
-				AfterExec:    "After Exec",
-				FinishRouter: "Finish Router"} {
-				if bf, ok := BeeApp.Handlers.filters[k]; ok {
-					filterType = fr
-					filterTypes = append(filterTypes, filterType)
-					resultList := new([][]string)
-					for _, f := range bf {
-						var result = []string{
-							fmt.Sprintf("%s", f.pattern),
-							fmt.Sprintf("%s", utils.GetFuncName(f.filterFunc)),
-						}
-						*resultList = append(*resultList, result)
+		filters := BeeApp.Handlers.Filters()
+		for _, fp := range filterPositions {
+			if bf, ok := filters[fp.pos]; ok {
+				filterTypes = append(filterTypes, fp.name)
+				resultList := new([][]string)
+				for _, f := range bf {
+					var result = []string{
+						f.Name,
+						f.Pattern,
+						strconv.Itoa(f.Priority),
+						strconv.FormatBool(f.ReturnOnOutput),
 					}
-					filterTypeData[filterType] = resultList
+					*resultList = append(*resultList, result)
 				}
+				filterTypeData[fp.name] = resultList
 			}
 		}
 
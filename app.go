@@ -37,10 +37,14 @@ func init() {
 	BeeApp = NewApp()
 }
 
+// MiddleWare function for http.Handler
+type MiddleWare func(http.Handler) http.Handler
+
 // App defines goweb application with a new PatternServeMux.
 type App struct {
-	Handlers *ControllerRegister
-	Server   *http.Server
+	Handlers    *ControllerRegister
+	Server      *http.Server
+	middleWares []MiddleWare
 }
 
 // NewApp returns a new goweb application.
@@ -50,8 +54,29 @@ func NewApp() *App {
 	return app
 }
 
+// Use appends MiddleWare which wraps the ControllerRegister when the application runs.
+// the first MiddleWare is the outermost one.
+// usage:
+//  goweb.BeeApp.Use(gziphandler.GzipHandler, tracing.Middleware)
+func (app *App) Use(mws ...MiddleWare) *App {
+	app.middleWares = append(app.middleWares, mws...)
+	return app
+}
+
+// Handler returns the http.Handler served by app, that is the ControllerRegister
+// wrapped by the MiddleWare added with Use and the given mws.
+func (app *App) Handler(mws ...MiddleWare) http.Handler {
+	var h http.Handler = app.Handlers
+	all := append(append([]MiddleWare{}, app.middleWares...), mws...)
+	for i := len(all) - 1; i >= 0; i-- {
+		h = all[i](h)
+	}
+	return h
+}
+
 // Run goweb application.
-func (app *App) Run() {
+// mws are applied after the MiddleWare added with Use.
+func (app *App) Run(mws ...MiddleWare) {
 	addr := BConfig.Listen.HTTPAddr
 
 	if BConfig.Listen.HTTPPort != 0 {
@@ -62,12 +87,13 @@ func (app *App) Run() {
 		err        error
 		l          net.Listener
 		endRunning = make(chan bool, 1)
+		handler    = app.Handler(mws...)
 	)
 
 	// run cgi server
 	if BConfig.Listen.EnableFcgi {
 		if BConfig.Listen.EnableStdIo {
-			if err = fcgi.Serve(nil, app.Handlers); err == nil { // standard I/O
+			if err = fcgi.Serve(nil, handler); err == nil { // standard I/O
 				BeeLogger.Info("Use FCGI via standard I/O")
 			} else {
 				BeeLogger.Critical("Cannot use FCGI via standard I/O", err)
@@ -86,13 +112,13 @@ func (app *App) Run() {
 		if err != nil {
 			BeeLogger.Critical("Listen: ", err)
 		}
-		if err = fcgi.Serve(l, app.Handlers); err != nil {
+		if err = fcgi.Serve(l, handler); err != nil {
 			BeeLogger.Critical("fcgi.Serve: ", err)
 		}
 		return
 	}
 
-	app.Server.Handler = app.Handlers
+	app.Server.Handler = handler
 	app.Server.ReadTimeout = time.Duration(BConfig.Listen.ServerTimeOut) * time.Second
 	app.Server.WriteTimeout = time.Duration(BConfig.Listen.ServerTimeOut) * time.Second
 
@@ -107,7 +133,7 @@ func (app *App) Run() {
 					httpsAddr = fmt.Sprintf("%s:%d", BConfig.Listen.HTTPSAddr, BConfig.Listen.HTTPSPort)
 					app.Server.Addr = httpsAddr
 				}
-				server := grace.NewServer(httpsAddr, app.Handlers)
+				server := grace.NewServer(httpsAddr, handler)
 				server.Server.ReadTimeout = app.Server.ReadTimeout
 				server.Server.WriteTimeout = app.Server.WriteTimeout
 				if err := server.ListenAndServeTLS(BConfig.Listen.HTTPSCertFile, BConfig.Listen.HTTPSKeyFile); err != nil {
@@ -119,7 +145,7 @@ func (app *App) Run() {
 		}
 		if BConfig.Listen.EnableHTTP {
 			go func() {
-				server := grace.NewServer(addr, app.Handlers)
+				server := grace.NewServer(addr, handler)
 				server.Server.ReadTimeout = app.Server.ReadTimeout
 				server.Server.WriteTimeout = app.Server.WriteTimeout
 				if BConfig.Listen.ListenTCP4 {
@@ -203,6 +229,62 @@ func Router(rootpath string, c ControllerInterface, mappingMethods ...string) *A
 	return BeeApp
 }
 
+// RouterWithChain adds a patterned controller handler wrapped by chains to BeeApp.
+// it's an alias method of ControllerRegister.AddWithChain.
+// usage:
+//  goweb.RouterWithChain("/admin", &admin.UserController{}, []goweb.FilterChain{Auth})
+//  goweb.RouterWithChain("/api/list", &RestController{}, []goweb.FilterChain{Auth, RateLimit}, "*:ListFood")
+func RouterWithChain(rootpath string, c ControllerInterface, chains []FilterChain, mappingMethods ...string) *App {
+	BeeApp.Handlers.AddWithChain(rootpath, c, chains, mappingMethods...)
+	return BeeApp
+}
+
+// Name sets the name of the last router added to BeeApp,
+// so that URLFor and the urlfor template func can build its url by name.
+// usage:
+//  goweb.Router("/user/:id", &UserController{}, "get:Show").Name("user.show")
+//  goweb.URLFor("user.show", ":id", 1)
+func (app *App) Name(name string) *App {
+	app.Handlers.Name(name)
+	return app
+}
+
+// Params sets the argument sources of the controller method of the last router added to BeeApp.
+// refer: ControllerRegister.Params
+// usage:
+//  goweb.Router("/user/:id", &UserController{}, "get:Show").Params("id:path", "q:query")
+func (app *App) Params(params ...string) *App {
+	app.Handlers.Params(params...)
+	return app
+}
+
+// Timeout sets the deadline of the last router added to BeeApp.
+// refer: ControllerRegister.Timeout
+// usage:
+//  goweb.Router("/report", &ReportController{}).Timeout(2 * time.Second)
+func (app *App) Timeout(d time.Duration, code ...int) *App {
+	app.Handlers.Timeout(d, code...)
+	return app
+}
+
+// Group returns a RouterGroup adding routers to the App under prefix.
+// refer: ControllerRegister.Group
+func (app *App) Group(prefix string, opts ...GroupOption) *RouterGroup {
+	return app.Handlers.Group(prefix, opts...)
+}
+
+// Group returns a RouterGroup adding routers to BeeApp under prefix,
+// whose filters only run for the routers of the group.
+// usage:
+//  api := goweb.Group("/api", goweb.GroupBefore(Auth))
+//  api.Router("/user", &UserController{})
+//  api.Get("/version", func(ctx *context.Context){
+//        ctx.Output.Body([]byte("1.0"))
+//  })
+func Group(prefix string, opts ...GroupOption) *RouterGroup {
+	return BeeApp.Handlers.Group(prefix, opts...)
+}
+
 // Include will generate router file in the router/xxx.go from the controller's comments
 // usage:
 // goweb.Include(&BankAccount{}, &OrderController{},&RefundController{},&ReceiptController{})
@@ -267,8 +349,8 @@ func AutoPrefix(prefix string, c ControllerInterface) *App {
 //    goweb.Get("/", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func Get(rootpath string, f FilterFunc) *App {
-	BeeApp.Handlers.Get(rootpath, f)
+func Get(rootpath string, f FilterFunc, chains ...FilterChain) *App {
+	BeeApp.Handlers.Get(rootpath, f, chains...)
 	return BeeApp
 }
 
@@ -277,8 +359,8 @@ func Get(rootpath string, f FilterFunc) *App {
 //    goweb.Post("/api", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func Post(rootpath string, f FilterFunc) *App {
-	BeeApp.Handlers.Post(rootpath, f)
+func Post(rootpath string, f FilterFunc, chains ...FilterChain) *App {
+	BeeApp.Handlers.Post(rootpath, f, chains...)
 	return BeeApp
 }
 
@@ -287,8 +369,8 @@ func Post(rootpath string, f FilterFunc) *App {
 //    goweb.Delete("/api", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func Delete(rootpath string, f FilterFunc) *App {
-	BeeApp.Handlers.Delete(rootpath, f)
+func Delete(rootpath string, f FilterFunc, chains ...FilterChain) *App {
+	BeeApp.Handlers.Delete(rootpath, f, chains...)
 	return BeeApp
 }
 
@@ -297,8 +379,8 @@ func Delete(rootpath string, f FilterFunc) *App {
 //    goweb.Put("/api", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func Put(rootpath string, f FilterFunc) *App {
-	BeeApp.Handlers.Put(rootpath, f)
+func Put(rootpath string, f FilterFunc, chains ...FilterChain) *App {
+	BeeApp.Handlers.Put(rootpath, f, chains...)
 	return BeeApp
 }
 
@@ -307,8 +389,8 @@ func Put(rootpath string, f FilterFunc) *App {
 //    goweb.Head("/api", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func Head(rootpath string, f FilterFunc) *App {
-	BeeApp.Handlers.Head(rootpath, f)
+func Head(rootpath string, f FilterFunc, chains ...FilterChain) *App {
+	BeeApp.Handlers.Head(rootpath, f, chains...)
 	return BeeApp
 }
 
@@ -317,8 +399,8 @@ func Head(rootpath string, f FilterFunc) *App {
 //    goweb.Options("/api", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func Options(rootpath string, f FilterFunc) *App {
-	BeeApp.Handlers.Options(rootpath, f)
+func Options(rootpath string, f FilterFunc, chains ...FilterChain) *App {
+	BeeApp.Handlers.Options(rootpath, f, chains...)
 	return BeeApp
 }
 
@@ -327,8 +409,8 @@ func Options(rootpath string, f FilterFunc) *App {
 //    goweb.Patch("/api", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func Patch(rootpath string, f FilterFunc) *App {
-	BeeApp.Handlers.Patch(rootpath, f)
+func Patch(rootpath string, f FilterFunc, chains ...FilterChain) *App {
+	BeeApp.Handlers.Patch(rootpath, f, chains...)
 	return BeeApp
 }
 
@@ -337,8 +419,8 @@ func Patch(rootpath string, f FilterFunc) *App {
 //    goweb.Any("/api", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func Any(rootpath string, f FilterFunc) *App {
-	BeeApp.Handlers.Any(rootpath, f)
+func Any(rootpath string, f FilterFunc, chains ...FilterChain) *App {
+	BeeApp.Handlers.Any(rootpath, f, chains...)
 	return BeeApp
 }
 
@@ -360,3 +442,17 @@ func InsertFilter(pattern string, pos int, filter FilterFunc, params ...bool) *A
 	BeeApp.Handlers.InsertFilter(pattern, pos, filter, params...)
 	return BeeApp
 }
+
+// InsertFilterWithOptions adds a FilterFunc to BeeApp with its name, priority and returnOnOutput set by options,
+// the filters of the same position run by priority, so that they don't depend on the import order.
+// refer: ControllerRegister.InsertFilterWithOptions
+// usage:
+//  goweb.InsertFilterWithOptions("/*", goweb.BeforeRouter, Auth, goweb.FilterName("auth"), goweb.FilterPriority(-10))
+func InsertFilterWithOptions(pattern string, pos int, filter FilterFunc, opts ...FilterOption) error {
+	return BeeApp.Handlers.InsertFilterWithOptions(pattern, pos, filter, opts...)
+}
+
+// RemoveFilter removes the filters of BeeApp named name by FilterName.
+func RemoveFilter(name string) bool {
+	return BeeApp.Handlers.RemoveFilter(name)
+}
@@ -0,0 +1,45 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"net/http"
+	"testing"
+)
+
+func tagMiddleWare(tag string) MiddleWare {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Add("X-Middleware", tag)
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func TestAppHandlerMiddleWare(t *testing.T) {
+	app := NewApp()
+	app.Handlers.Get("/hello", gowebFilterFunc)
+	app.Use(tagMiddleWare("a"), tagMiddleWare("b"))
+
+	rw, r := testRequest("GET", "/hello")
+	app.Handler(tagMiddleWare("c")).ServeHTTP(rw, r)
+	if rw.Body.String() != "hello" {
+		t.Errorf("wrapped ControllerRegister can't run, get %q", rw.Body.String())
+	}
+	tags := rw.Header()["X-Middleware"]
+	if len(tags) != 3 || tags[0] != "a" || tags[1] != "b" || tags[2] != "c" {
+		t.Errorf("MiddleWare should run in declared order, get %v", tags)
+	}
+}
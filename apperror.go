@@ -0,0 +1,172 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/cooleo/goweb/context"
+)
+
+// AppError is an application error with the http status to respond.
+// a controller can panic with it, or return it from a method with arguments,
+// it's rendered by the error mode instead of being logged as a crash.
+// Code is the application error code, eg. "user_not_found",
+// and Details is the payload of the error.
+type AppError struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+	Err     error
+}
+
+// NewAppError returns the AppError of status, the message is the status text if it's empty.
+// usage:
+//	var ErrUserNotFound = goweb.NewAppError(404, "user_not_found", "the user doesn't exist")
+//	panic(ErrUserNotFound.Wrap(err))
+func NewAppError(status int, code, message string) *AppError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &AppError{Status: status, Code: code, Message: message}
+}
+
+// Error returns the message with the cause.
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the cause of the error.
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an AppError of the same status and code,
+// so that the copies returned by Wrap and WithDetails match their origin.
+// the errors without code only match themselves.
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code != "" && t.Status == e.Status && t.Code == e.Code
+}
+
+// Wrap returns a copy of the error caused by err.
+func (e *AppError) Wrap(err error) *AppError {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// WithDetails returns a copy of the error with the details.
+func (e *AppError) WithDetails(details interface{}) *AppError {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// errorStatus maps the errors matching target, or of type typ, to status.
+type errorStatus struct {
+	target error
+	typ    reflect.Type
+	status int
+}
+
+var (
+	errorStatusesLock sync.RWMutex
+	errorStatuses     []errorStatus
+)
+
+// RegisterErrorStatus maps the errors matching target by errors.Is to status,
+// so that they are rendered as the AppError of status.
+// usage:
+//	goweb.RegisterErrorStatus(orm.ErrNoRows, 404)
+func RegisterErrorStatus(target error, status int) *App {
+	errorStatusesLock.Lock()
+	defer errorStatusesLock.Unlock()
+	errorStatuses = append(errorStatuses, errorStatus{target: target, status: status})
+	return BeeApp
+}
+
+// RegisterErrorType maps the errors of the type of sample in the chain to status.
+// usage:
+//	goweb.RegisterErrorType(&models.ValidationError{}, 422)
+func RegisterErrorType(sample error, status int) *App {
+	errorStatusesLock.Lock()
+	defer errorStatusesLock.Unlock()
+	errorStatuses = append(errorStatuses, errorStatus{typ: reflect.TypeOf(sample), status: status})
+	return BeeApp
+}
+
+func registeredStatus(err error) (int, bool) {
+	errorStatusesLock.RLock()
+	defer errorStatusesLock.RUnlock()
+	for _, es := range errorStatuses {
+		if es.target != nil {
+			if errors.Is(err, es.target) {
+				return es.status, true
+			}
+			continue
+		}
+		for e := err; e != nil; e = errors.Unwrap(e) {
+			if reflect.TypeOf(e) == es.typ {
+				return es.status, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// appError returns the AppError of an expected error, which is an AppError,
+// a BindError or registered, and nil for the others.
+func appError(err error) *AppError {
+	var ae *AppError
+	if errors.As(err, &ae) {
+		return ae
+	}
+	var be *context.BindError
+	if errors.As(err, &be) {
+		return &AppError{Status: be.Status, Message: be.Message, Err: be}
+	}
+	if status, ok := registeredStatus(err); ok {
+		return &AppError{Status: status, Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+// renderAppError renders the error as a problem in the problem error mode,
+// otherwise by ErrorMaps with the error in the input data "Error",
+// or as the message if there is no handler of the status.
+func renderAppError(ctx *context.Context, e *AppError) {
+	if problemMode(ctx) {
+		writeProblem(ctx, errorProblem(e.Status, e))
+		return
+	}
+	code := strconv.Itoa(e.Status)
+	if _, ok := ErrorMaps[code]; ok {
+		ctx.Input.SetData("Error", e)
+		exception(code, ctx)
+		return
+	}
+	ctx.Output.Header("Content-Type", "text/plain; charset=utf-8")
+	ctx.Output.SetStatus(e.Status)
+	ctx.Output.Body([]byte(e.Message))
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var (
+	errUserNotFound = NewAppError(http.StatusNotFound, "user_not_found", "the user doesn't exist")
+	errUserGone     = errors.New("the user is gone")
+)
+
+type userLockedError struct{}
+
+func (userLockedError) Error() string { return "the user is locked" }
+
+type AppErrorController struct {
+	Controller
+}
+
+func (a *AppErrorController) Get() {
+	panic(errUserNotFound.WithDetails(map[string]string{"id": a.Ctx.Input.Param(":id")}))
+}
+
+func (a *AppErrorController) Find(id string) (string, error) {
+	switch id {
+	case "gone":
+		return "", fmt.Errorf("find %s: %w", id, errUserGone)
+	case "locked":
+		return "", fmt.Errorf("find %s: %w", id, userLockedError{})
+	}
+	return "", errUserNotFound.Wrap(errors.New("no row found"))
+}
+
+func TestAppError(t *testing.T) {
+	n := len(errorStatuses)
+	RegisterErrorStatus(errUserGone, http.StatusGone)
+	RegisterErrorType(userLockedError{}, http.StatusLocked)
+	defer func() { errorStatuses = errorStatuses[:n] }()
+	errorMode := BConfig.ErrorMode
+	BConfig.ErrorMode = ErrorModeAuto
+	defer func() { BConfig.ErrorMode = errorMode }()
+
+	handler := NewControllerRegister()
+	handler.Add("/user/:id", &AppErrorController{})
+	handler.Add("/find/:id", &AppErrorController{}, "get:Find")
+
+	tests := []struct {
+		url    string
+		accept string
+		status int
+		body   string
+	}{
+		{"/user/7", "application/json", 404, `"code":"user_not_found","detail":"the user doesn't exist","details":{"id":"7"}`},
+		{"/user/7", "text/html", 404, "the user doesn't exist"},
+		{"/find/8", "application/xml", 404, `<error code="user_not_found">the user doesn&#39;t exist</error>`},
+		{"/find/gone", "application/json", 410, `"detail":"find gone: the user is gone"`},
+		{"/find/locked", "application/xml", 423, `<error>find locked: the user is locked</error>`},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.url, nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s should get %d %s, get %d %s", tt.url, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+	if !errors.Is(errUserNotFound.Wrap(errUserGone), errUserNotFound) || !errors.Is(errUserNotFound.Wrap(errUserGone), errUserGone) {
+		t.Error("the wrapped AppError should match its origin and cause")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/cooleo/goweb/context"
+	"github.com/cooleo/goweb/validation"
+)
+
+func init() {
+	context.AddBodyDecoder("application/x-www-form-urlencoded", decodeFormBody)
+	context.AddBodyDecoder("multipart/form-data", decodeFormBody)
+}
+
+// decodeFormBody maps the form values into the struct of dst by ParseForm,
+// dst can be a pointer to a struct pointer, which is allocated.
+func decodeFormBody(ctx *context.Context, dst interface{}, maxMemory int64) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Ptr && v.Elem().Type().Elem().Kind() == reflect.Struct {
+		if v.Elem().IsNil() {
+			v.Elem().Set(reflect.New(v.Elem().Type().Elem()))
+		}
+		dst = v.Elem().Interface()
+	}
+	if len(ctx.Input.RequestBody) == 0 {
+		if ctx.Request.ContentLength > maxMemory {
+			return context.NewBindError(http.StatusRequestEntityTooLarge, "", nil)
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.ResponseWriter, ctx.Request.Body, maxMemory)
+	}
+	if err := ctx.Input.ParseFormOrMulitForm(maxMemory); err != nil {
+		return err
+	}
+	return ParseForm(ctx.Request.Form, dst)
+}
+
+// validBody runs validation.Valid on the bound body,
+// and returns a 400 BindError with the invalid fields.
+func validBody(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	for v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	valid := validation.Validation{}
+	ok, err := valid.Valid(v.Interface())
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	be := context.NewBindError(http.StatusBadRequest, "the request body is invalid", nil)
+	be.Fields = make(map[string]string, len(valid.Errors))
+	for _, e := range valid.Errors {
+		field := e.Field
+		if field == "" {
+			field = e.Key
+		}
+		if _, ok := be.Fields[field]; !ok {
+			be.Fields[field] = e.Message
+		}
+	}
+	return be
+}
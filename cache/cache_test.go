@@ -15,6 +15,7 @@
 package cache
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -166,3 +167,28 @@ func TestFileCache(t *testing.T) {
 
 	os.RemoveAll("cache")
 }
+
+func TestContextCache(t *testing.T) {
+	bm, err := NewCache("memory", `{"interval":20}`)
+	if err != nil {
+		t.Error("init err")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := WithContext(ctx, bm)
+	if err = c.Put("cooleo", 1, 10*time.Second); err != nil {
+		t.Error("set Error", err)
+	}
+	if v := c.Get("cooleo"); v.(int) != 1 {
+		t.Error("get err")
+	}
+	cancel()
+	if err = c.Put("cooleo", 2, 10*time.Second); err != context.Canceled {
+		t.Error("set should return context.Canceled, get", err)
+	}
+	if c.Get("cooleo") != nil || c.IsExist("cooleo") {
+		t.Error("get should return nil when the context is done")
+	}
+	if v := bm.Get("cooleo"); v.(int) != 1 {
+		t.Error("cache should not be changed when the context is done")
+	}
+}
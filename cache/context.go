@@ -0,0 +1,95 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// WithContext returns a Cache bound to ctx, which doesn't reach
+// the adapter any more once ctx is done.
+// the writes return ctx.Err(), the reads return nil or false.
+// usage:
+//	c := cache.WithContext(ctx.Request.Context(), bm)
+//	c.Put("cooleo", 1, 10 * time.Second)
+func WithContext(ctx context.Context, adapter Cache) Cache {
+	return &contextCache{ctx: ctx, adapter: adapter}
+}
+
+type contextCache struct {
+	ctx     context.Context
+	adapter Cache
+}
+
+func (c *contextCache) Get(key string) interface{} {
+	if c.ctx.Err() != nil {
+		return nil
+	}
+	return c.adapter.Get(key)
+}
+
+func (c *contextCache) GetMulti(keys []string) []interface{} {
+	if c.ctx.Err() != nil {
+		return make([]interface{}, len(keys))
+	}
+	return c.adapter.GetMulti(keys)
+}
+
+func (c *contextCache) Put(key string, val interface{}, timeout time.Duration) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	return c.adapter.Put(key, val, timeout)
+}
+
+func (c *contextCache) Delete(key string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	return c.adapter.Delete(key)
+}
+
+func (c *contextCache) Incr(key string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	return c.adapter.Incr(key)
+}
+
+func (c *contextCache) Decr(key string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	return c.adapter.Decr(key)
+}
+
+func (c *contextCache) IsExist(key string) bool {
+	if c.ctx.Err() != nil {
+		return false
+	}
+	return c.adapter.IsExist(key)
+}
+
+func (c *contextCache) ClearAll() error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	return c.adapter.ClearAll()
+}
+
+func (c *contextCache) StartAndGC(config string) error {
+	return c.adapter.StartAndGC(config)
+}
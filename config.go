@@ -27,18 +27,24 @@ import (
 
 // Config is the main struct for BConfig
 type Config struct {
-	AppName             string //Application name
-	RunMode             string //Running Mode: dev | prod
-	RouterCaseSensitive bool
-	ServerName          string
-	RecoverPanic        bool
-	CopyRequestBody     bool
-	EnableGzip          bool
-	MaxMemory           int64
-	EnableErrorsShow    bool
-	Listen              Listen
-	WebConfig           WebConfig
-	Log                 LogConfig
+	AppName               string //Application name
+	RunMode               string //Running Mode: dev | prod
+	RouterCaseSensitive   bool
+	RouterRedirectCase    bool   // redirect the path to lower case rather than matching it lowercased, with RouterCaseSensitive false
+	RouterCleanPath       bool   // redirect the path with duplicate slashes, . and .. to the cleaned one
+	RouterTrailingSlash   string // "strip" or "append" redirects to the path without or with the trailing slash
+	RouterRedirectCode    int    // 301 or 308 for the path redirects, 0 uses 301 for GET and HEAD and 308 for the others
+	ServerName            string
+	RecoverPanic          bool
+	CopyRequestBody       bool
+	EnableGzip            bool
+	MaxMemory             int64
+	EnableErrorsShow      bool
+	ErrorMode             string // "html", "auto" renders the errors as application/problem+json for the clients accepting json, "problem" always does
+	AlwaysRunAfterFilters bool   // run AfterExec and FinishRouter filters for the requests stopped before the router
+	Listen                Listen
+	WebConfig             WebConfig
+	Log                   LogConfig
 }
 
 // Listen holds for http and https related config
@@ -73,6 +79,7 @@ type WebConfig struct {
 	TemplateLeft           string
 	TemplateRight          string
 	ViewsPath              string
+	EnableLiveReload       bool // reload the pages when the templates change in the dev mode
 	EnableXSRF             bool
 	XSRFKey                string
 	XSRFExpire             int
@@ -129,6 +136,7 @@ func init() {
 		EnableGzip:          false,
 		MaxMemory:           1 << 26, //64MB
 		EnableErrorsShow:    true,
+		ErrorMode:           ErrorModeHTML,
 		Listen: Listen{
 			Graceful:      false,
 			ServerTimeOut: 0,
@@ -158,6 +166,7 @@ func init() {
 			TemplateLeft:           "{{",
 			TemplateRight:          "}}",
 			ViewsPath:              "views",
+			EnableLiveReload:       false,
 			EnableXSRF:             false,
 			XSRFKey:                "gowebxsrf",
 			XSRFExpire:             0,
@@ -206,10 +215,16 @@ func parseConfig(appConfigPath string) (err error) {
 	BConfig.AppName = AppConfig.DefaultString("AppName", BConfig.AppName)
 	BConfig.RecoverPanic = AppConfig.DefaultBool("RecoverPanic", BConfig.RecoverPanic)
 	BConfig.RouterCaseSensitive = AppConfig.DefaultBool("RouterCaseSensitive", BConfig.RouterCaseSensitive)
+	BConfig.RouterRedirectCase = AppConfig.DefaultBool("RouterRedirectCase", BConfig.RouterRedirectCase)
+	BConfig.RouterCleanPath = AppConfig.DefaultBool("RouterCleanPath", BConfig.RouterCleanPath)
+	BConfig.RouterTrailingSlash = AppConfig.DefaultString("RouterTrailingSlash", BConfig.RouterTrailingSlash)
+	BConfig.RouterRedirectCode = AppConfig.DefaultInt("RouterRedirectCode", BConfig.RouterRedirectCode)
 	BConfig.ServerName = AppConfig.DefaultString("ServerName", BConfig.ServerName)
 	BConfig.EnableGzip = AppConfig.DefaultBool("EnableGzip", BConfig.EnableGzip)
 	BConfig.EnableErrorsShow = AppConfig.DefaultBool("EnableErrorsShow", BConfig.EnableErrorsShow)
 	BConfig.CopyRequestBody = AppConfig.DefaultBool("CopyRequestBody", BConfig.CopyRequestBody)
+	BConfig.ErrorMode = AppConfig.DefaultString("ErrorMode", BConfig.ErrorMode)
+	BConfig.AlwaysRunAfterFilters = AppConfig.DefaultBool("AlwaysRunAfterFilters", BConfig.AlwaysRunAfterFilters)
 	BConfig.MaxMemory = AppConfig.DefaultInt64("MaxMemory", BConfig.MaxMemory)
 	BConfig.Listen.Graceful = AppConfig.DefaultBool("Graceful", BConfig.Listen.Graceful)
 	BConfig.Listen.HTTPAddr = AppConfig.String("HTTPAddr")
@@ -229,6 +244,7 @@ func parseConfig(appConfigPath string) (err error) {
 	BConfig.Listen.ServerTimeOut = AppConfig.DefaultInt64("ServerTimeOut", BConfig.Listen.ServerTimeOut)
 	BConfig.WebConfig.AutoRender = AppConfig.DefaultBool("AutoRender", BConfig.WebConfig.AutoRender)
 	BConfig.WebConfig.ViewsPath = AppConfig.DefaultString("ViewsPath", BConfig.WebConfig.ViewsPath)
+	BConfig.WebConfig.EnableLiveReload = AppConfig.DefaultBool("EnableLiveReload", BConfig.WebConfig.EnableLiveReload)
 	BConfig.WebConfig.DirectoryIndex = AppConfig.DefaultBool("DirectoryIndex", BConfig.WebConfig.DirectoryIndex)
 	BConfig.WebConfig.FlashName = AppConfig.DefaultString("FlashName", BConfig.WebConfig.FlashName)
 	BConfig.WebConfig.FlashSeparator = AppConfig.DefaultString("FlashSeparator", BConfig.WebConfig.FlashSeparator)
@@ -0,0 +1,183 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ConstraintFunc checks the value of a router param.
+// args are the comma separated values in the brackets of the pattern,
+// eg. ":code:len[2,3]" calls the "len" ConstraintFunc with args ["2" "3"].
+type ConstraintFunc func(value string, args []string) bool
+
+var (
+	paramConstraintsLock sync.RWMutex
+	paramConstraints     = map[string]ConstraintFunc{
+		"uuid":  regexpConstraint(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+		"slug":  regexpConstraint(`^[a-z0-9]+(?:-[a-z0-9]+)*$`),
+		"hex":   regexpConstraint(`^[0-9a-fA-F]+$`),
+		"alpha": regexpConstraint(`^[a-zA-Z]+$`),
+		"alnum": regexpConstraint(`^[a-zA-Z0-9]+$`),
+		"date":  dateConstraint,
+		"len":   lenConstraint,
+		"range": rangeConstraint,
+		"enum":  enumConstraint,
+	}
+	constraintNameRegex  = regexp.MustCompile(`^[a-zA-Z0-9_]+`)
+	constraintParamRegex = regexp.MustCompile(`:[a-zA-Z0-9_]+:`)
+)
+
+// AddParamConstraint registers a ConstraintFunc which can be used in router pattern as ":param:name".
+// the router doesn't match the request when the ConstraintFunc returns false.
+// it must be called before the routers using it are added.
+// usage:
+//
+//	AddParamConstraint("even", func(value string, args []string) bool {
+//		i, err := strconv.Atoi(value)
+//		return err == nil && i%2 == 0
+//	})
+//	Router("/page/:num:even", &PageController{})
+//
+// the builtin constraints are
+//
+//	:id:uuid            8-4-4-4-12 hex digits
+//	:name:slug          lower case words joined by "-"
+//	:id:hex             hex digits
+//	:name:alpha         letters
+//	:name:alnum         letters and digits
+//	:day:date           2006-01-02, or the layout in brackets as :day:date[20060102]
+//	:code:len[3]        3 characters, :code:len[2,8] from 2 to 8 characters
+//	:page:range[1,100]  integer from 1 to 100
+//	:lang:enum[en,fr]   one of the values in brackets
+func AddParamConstraint(name string, fn ConstraintFunc) {
+	paramConstraintsLock.Lock()
+	defer paramConstraintsLock.Unlock()
+	paramConstraints[name] = fn
+}
+
+type paramConstraint struct {
+	name string
+	args []string
+	fn   ConstraintFunc
+}
+
+func (c *paramConstraint) check(value string) bool {
+	return c.fn(value, c.args)
+}
+
+// parseConstraint parses the constraint at the beginning of s, which is the text
+// following the ":" after the param name, and returns the rune count of its text.
+// it returns nil if the constraint isn't registered.
+// "uuid/other" -> uuid, 4
+// "len[2,3]_x" -> len [2 3], 8
+func parseConstraint(s string) (*paramConstraint, int) {
+	name := constraintNameRegex.FindString(s)
+	paramConstraintsLock.RLock()
+	fn, ok := paramConstraints[name]
+	paramConstraintsLock.RUnlock()
+	if name == "" || !ok {
+		return nil, 0
+	}
+	c := &paramConstraint{name: name, fn: fn}
+	text := name
+	if rest := s[len(name):]; strings.HasPrefix(rest, "[") {
+		if end := strings.Index(rest, "]"); end > 0 {
+			c.args = strings.Split(rest[1:end], ",")
+			text += rest[:end+1]
+		}
+	}
+	return c, utf8.RuneCountInString(text)
+}
+
+// patternConstraints returns the constraints of all the params in pattern.
+// "/user/:id:uuid/:page:range[1,9]" -> {":id": uuid, ":page": range [1 9]}
+func patternConstraints(pattern string) map[string]*paramConstraint {
+	var constraints map[string]*paramConstraint
+	for _, loc := range constraintParamRegex.FindAllStringIndex(pattern, -1) {
+		if c, _ := parseConstraint(pattern[loc[1]:]); c != nil {
+			if constraints == nil {
+				constraints = make(map[string]*paramConstraint)
+			}
+			constraints[pattern[loc[0]:loc[1]-1]] = c
+		}
+	}
+	return constraints
+}
+
+func regexpConstraint(expr string) ConstraintFunc {
+	reg := regexp.MustCompile(expr)
+	return func(value string, args []string) bool {
+		return reg.MatchString(value)
+	}
+}
+
+func dateConstraint(value string, args []string) bool {
+	layout := "2006-01-02"
+	if len(args) > 0 && args[0] != "" {
+		layout = args[0]
+	}
+	_, err := time.Parse(layout, value)
+	return err == nil
+}
+
+func lenConstraint(value string, args []string) bool {
+	n := int64(utf8.RuneCountInString(value))
+	switch len(args) {
+	case 1:
+		l, err := strconv.ParseInt(args[0], 10, 64)
+		return err == nil && n == l
+	case 2:
+		return inRange(n, args[0], args[1])
+	}
+	return false
+}
+
+func rangeConstraint(value string, args []string) bool {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || len(args) != 2 {
+		return false
+	}
+	return inRange(n, args[0], args[1])
+}
+
+func enumConstraint(value string, args []string) bool {
+	for _, v := range args {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// inRange reports min <= n <= max, an empty min or max means no bound.
+func inRange(n int64, min, max string) bool {
+	if min != "" {
+		if m, err := strconv.ParseInt(min, 10, 64); err != nil || n < m {
+			return false
+		}
+	}
+	if max != "" {
+		if m, err := strconv.ParseInt(max, 10, 64); err != nil || n > m {
+			return false
+		}
+	}
+	return true
+}
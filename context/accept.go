@@ -0,0 +1,165 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MediaRange is a media range of the Accept header with its quality.
+type MediaRange struct {
+	Type    string
+	Subtype string
+	Params  map[string]string
+	Q       float64
+}
+
+// specificity ranks */* < type/* < type/subtype < type/subtype;params.
+func (m MediaRange) specificity() int {
+	switch {
+	case m.Type == "*":
+		return 0
+	case m.Subtype == "*":
+		return 1
+	case len(m.Params) == 0:
+		return 2
+	}
+	return 3
+}
+
+// match returns whether the media type is in the range.
+func (m MediaRange) match(typ, subtype string, params map[string]string) bool {
+	if m.Type != "*" && m.Type != typ {
+		return false
+	}
+	if m.Subtype != "*" && m.Subtype != subtype {
+		return false
+	}
+	for k, v := range m.Params {
+		if params[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseAccept parses the Accept header into the media ranges,
+// ordered by quality and then by specificity.
+// the ranges of quality 0 are kept, they reject the media types.
+func ParseAccept(accept string) []MediaRange {
+	var ranges []MediaRange
+	for _, part := range strings.Split(accept, ",") {
+		typ, subtype, params := parseMediaType(part)
+		if typ == "" {
+			continue
+		}
+		m := MediaRange{Type: typ, Subtype: subtype, Q: 1}
+		for k, v := range params {
+			if k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil && q >= 0 && q <= 1 {
+					m.Q = q
+				}
+				continue
+			}
+			if m.Params == nil {
+				m.Params = make(map[string]string)
+			}
+			m.Params[k] = v
+		}
+		ranges = append(ranges, m)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].Q != ranges[j].Q {
+			return ranges[i].Q > ranges[j].Q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// parseMediaType splits "type/subtype;k=v" in lower case,
+// type is empty if it's malformed.
+func parseMediaType(s string) (typ, subtype string, params map[string]string) {
+	parts := strings.Split(s, ";")
+	full := strings.ToLower(strings.TrimSpace(parts[0]))
+	if full == "*" {
+		full = "*/*"
+	}
+	i := strings.Index(full, "/")
+	if i <= 0 || i == len(full)-1 {
+		return "", "", nil
+	}
+	typ, subtype = full[:i], full[i+1:]
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+	}
+	return
+}
+
+// NegotiateContentType returns the offered media type the request accepts best,
+// the quality of an offer is the one of its most specific media range,
+// the earlier offer wins the same quality.
+// it returns the first offer if there is no Accept header,
+// and an empty string if none of the offers is acceptable.
+// usage:
+//	switch ctx.Input.NegotiateContentType("application/json", "text/html") {
+//	case "text/html":
+//	case "application/json":
+//	default:
+//		ctx.Abort(406, "not acceptable")
+//	}
+func (input *gowebInput) NegotiateContentType(offers ...string) string {
+	accept := input.Header("Accept")
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) > 0 {
+			return offers[0]
+		}
+		return ""
+	}
+	return negotiate(ParseAccept(accept), offers)
+}
+
+func negotiate(ranges []MediaRange, offers []string) string {
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, offer := range offers {
+		typ, subtype, params := parseMediaType(offer)
+		if typ == "" {
+			continue
+		}
+		// the most specific matching range decides the quality
+		q, spec := -1.0, -1
+		for _, m := range ranges {
+			if m.match(typ, subtype, params) && m.specificity() > spec {
+				q, spec = m.Q, m.specificity()
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
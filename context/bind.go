@@ -0,0 +1,181 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// BodyDecoder decodes the request body of ctx into dst,
+// the body is read up to maxMemory bytes.
+type BodyDecoder func(ctx *Context, dst interface{}, maxMemory int64) error
+
+var (
+	bodyDecodersLock sync.RWMutex
+	bodyDecoders     = map[string]BodyDecoder{
+		"application/json": decodeJSONBody,
+		"application/xml":  decodeXMLBody,
+		"text/xml":         decodeXMLBody,
+	}
+)
+
+// AddBodyDecoder registers the BodyDecoder of the media type of Content-Type for Input.BindBody,
+// it replaces the BodyDecoder registered for mediaType.
+// the media types suffixed by +json and +xml are decoded as json and xml if they aren't registered.
+// usage:
+//	context.AddBodyDecoder("application/x-yaml", func(ctx *context.Context, dst interface{}, maxMemory int64) error {
+//		body, err := ctx.Input.ReadBody(maxMemory)
+//		if err != nil {
+//			return err
+//		}
+//		return yaml.Unmarshal(body, dst)
+//	})
+func AddBodyDecoder(mediaType string, d BodyDecoder) {
+	bodyDecodersLock.Lock()
+	defer bodyDecodersLock.Unlock()
+	bodyDecoders[strings.ToLower(mediaType)] = d
+}
+
+func bodyDecoder(mediaType string) (BodyDecoder, bool) {
+	bodyDecodersLock.RLock()
+	defer bodyDecodersLock.RUnlock()
+	if d, ok := bodyDecoders[mediaType]; ok {
+		return d, true
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return decodeJSONBody, true
+	case strings.HasSuffix(mediaType, "+xml"):
+		return decodeXMLBody, true
+	}
+	return nil, false
+}
+
+// BindError is the error of Input.BindBody, whose Status is the http status to respond,
+// 400 for a malformed or invalid body, 413 for a too large body and 415 for an unsupported Content-Type.
+// Fields maps the invalid fields to their messages.
+type BindError struct {
+	XMLName xml.Name          `json:"-" xml:"error"`
+	Status  int               `json:"status" xml:"status"`
+	Message string            `json:"message" xml:"message"`
+	Fields  map[string]string `json:"fields,omitempty" xml:"-"`
+	Err     error             `json:"-" xml:"-"`
+}
+
+// NewBindError returns a BindError of status.
+// the message is the error of err if it's empty.
+func NewBindError(status int, message string, err error) *BindError {
+	if message == "" && err != nil {
+		message = err.Error()
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &BindError{Status: status, Message: message, Err: err}
+}
+
+func (e *BindError) Error() string {
+	return e.Message
+}
+
+// ReadBody returns the request body read up to maxMemory bytes, and keeps it in RequestBody.
+// it returns a 413 BindError if the body is larger.
+func (input *gowebInput) ReadBody(maxMemory int64) ([]byte, error) {
+	if len(input.RequestBody) > 0 {
+		if int64(len(input.RequestBody)) > maxMemory {
+			return nil, NewBindError(http.StatusRequestEntityTooLarge, "", nil)
+		}
+		return input.RequestBody, nil
+	}
+	r := input.Context.Request
+	if r.Body == nil {
+		return nil, nil
+	}
+	if r.ContentLength > maxMemory {
+		return nil, NewBindError(http.StatusRequestEntityTooLarge, "", nil)
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(input.Context.ResponseWriter, r.Body, maxMemory))
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		if _, ok := err.(*http.MaxBytesError); ok {
+			return nil, NewBindError(http.StatusRequestEntityTooLarge, "", err)
+		}
+		return nil, NewBindError(http.StatusBadRequest, "", err)
+	}
+	input.RequestBody = body
+	return body, nil
+}
+
+// BindBody decodes the request body into dst by the BodyDecoder of Content-Type,
+// json and xml are decoded by default, the forms are decoded once the goweb package is imported.
+// the body is read up to maxMemory bytes, and nothing is decoded if it's empty.
+// the errors are BindError.
+// usage:
+//	var u User
+//	if err := ctx.Input.BindBody(&u, goweb.BConfig.MaxMemory); err != nil {
+//		be := err.(*context.BindError)
+//		ctx.Output.SetStatus(be.Status)
+//		ctx.Output.JSON(be, false, false)
+//		return
+//	}
+func (input *gowebInput) BindBody(dst interface{}, maxMemory int64) error {
+	r := input.Context.Request
+	if (r.Body == nil || r.Body == http.NoBody) && len(input.RequestBody) == 0 {
+		return nil
+	}
+	contentType := input.Header("Content-Type")
+	if contentType == "" {
+		return NewBindError(http.StatusUnsupportedMediaType, "Content-Type is required", nil)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return NewBindError(http.StatusUnsupportedMediaType, "", err)
+	}
+	d, ok := bodyDecoder(mediaType)
+	if !ok {
+		return NewBindError(http.StatusUnsupportedMediaType, "Content-Type "+mediaType+" is not supported", nil)
+	}
+	if err := d(input.Context, dst, maxMemory); err != nil {
+		if _, ok := err.(*BindError); ok {
+			return err
+		}
+		return NewBindError(http.StatusBadRequest, "", err)
+	}
+	return nil
+}
+
+func decodeJSONBody(ctx *Context, dst interface{}, maxMemory int64) error {
+	body, err := ctx.Input.ReadBody(maxMemory)
+	if err != nil || len(body) == 0 {
+		return err
+	}
+	return json.Unmarshal(body, dst)
+}
+
+func decodeXMLBody(ctx *Context, dst interface{}, maxMemory int64) error {
+	body, err := ctx.Input.ReadBody(maxMemory)
+	if err != nil || len(body) == 0 {
+		return err
+	}
+	return xml.Unmarshal(body, dst)
+}
@@ -0,0 +1,63 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBindBody(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name"`
+		Age  int    `json:"age" xml:"age"`
+	}
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+		name        string
+	}{
+		{"application/json; charset=utf-8", `{"name":"cooleo","age":7}`, 0, "cooleo"},
+		{"application/vnd.goweb+json", `{"name":"vnd","age":8}`, 0, "vnd"},
+		{"text/xml", `<user><name>xml</name><age>9</age></user>`, 0, "xml"},
+		{"application/json", "", 0, ""},
+		{"application/json", `{"name":`, http.StatusBadRequest, ""},
+		{"application/json", `{"name":"` + strings.Repeat("x", 64) + `"}`, http.StatusRequestEntityTooLarge, ""},
+		{"text/csv", "name,age", http.StatusUnsupportedMediaType, ""},
+		{"", `{"name":"cooleo"}`, http.StatusUnsupportedMediaType, ""},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("POST", "/bind", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		ctx := NewContext()
+		ctx.Reset(httptest.NewRecorder(), r)
+		var u user
+		err := ctx.Input.BindBody(&u, 64)
+		if tt.status == 0 {
+			if err != nil || u.Name != tt.name {
+				t.Errorf("%s %s should bind %s, get %s, %v", tt.contentType, tt.body, tt.name, u.Name, err)
+			}
+			continue
+		}
+		if be, ok := err.(*BindError); !ok || be.Status != tt.status {
+			t.Errorf("%s %s should fail with %d, get %v", tt.contentType, tt.body, tt.status, err)
+		}
+	}
+}
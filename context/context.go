@@ -25,6 +25,7 @@ package context
 import (
 	"bufio"
 	"bytes"
+	gocontext "context"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
@@ -56,8 +57,25 @@ type Context struct {
 	Request        *http.Request
 	ResponseWriter *Response
 	_xsrfToken     string
+	filterAction   FilterAction
+	aborted        bool
 }
 
+// FilterAction is how a filter continues the request, set by Next, SkipFilters and AbortWith.
+type FilterAction int
+
+// the actions of a filter
+const (
+	// FilterDefault stops the filters once the response is written if the filter is returnOnOutput
+	FilterDefault FilterAction = iota
+	// FilterNext continues the remaining filters of the position even if the response is written
+	FilterNext
+	// FilterSkip skips the remaining filters of the position and continues the request
+	FilterSkip
+	// FilterAbort stops the request, the router and the filters before it are skipped
+	FilterAbort
+)
+
 // Reset init Context, gowebInput and gowebOutput
 func (ctx *Context) Reset(rw http.ResponseWriter, r *http.Request) {
 	ctx.Request = r
@@ -67,6 +85,43 @@ func (ctx *Context) Reset(rw http.ResponseWriter, r *http.Request) {
 	ctx.ResponseWriter.reset(rw)
 	ctx.Input.Reset(ctx)
 	ctx.Output.Reset(ctx)
+	ctx.filterAction = FilterDefault
+	ctx.aborted = false
+}
+
+// Context returns the context.Context of the request.
+// goweb cancels it when the client disconnects, the ServerTimeOut elapses
+// or the request is finished, so pass it to the downstream calls.
+func (ctx *Context) Context() gocontext.Context {
+	if ctx.Request == nil {
+		return gocontext.Background()
+	}
+	return ctx.Request.Context()
+}
+
+// SetContext replaces the context.Context of the request,
+// e.g. to carry request-scoped values or a shorter deadline.
+func (ctx *Context) SetContext(c gocontext.Context) {
+	ctx.Request = ctx.Request.WithContext(c)
+}
+
+// Clone returns a copy of ctx serving the request r with rw,
+// the router params, data and session of ctx are copied,
+// so that the copy can be used in another goroutine.
+func (ctx *Context) Clone(rw http.ResponseWriter, r *http.Request) *Context {
+	c := NewContext()
+	c.Reset(rw, r)
+	c.Input.CruSession = ctx.Input.CruSession
+	c.Input.RequestBody = ctx.Input.RequestBody
+	c.Input.pnames = append(c.Input.pnames, ctx.Input.pnames...)
+	c.Input.pvalues = append(c.Input.pvalues, ctx.Input.pvalues...)
+	for k, v := range ctx.Input.data {
+		c.Input.SetData(k, v)
+	}
+	c.Output.Status = ctx.Output.Status
+	c.Output.EnableGzip = ctx.Output.EnableGzip
+	c._xsrfToken = ctx._xsrfToken
+	return c
 }
 
 // Redirect does redirection to localurl with http header status code.
@@ -82,6 +137,45 @@ func (ctx *Context) Abort(status int, body string) {
 	panic(body)
 }
 
+// AbortWith stops the request in a filter, with the response of status and body.
+// the remaining filters of the position and the router are skipped,
+// a status of 0 stops the request without writing the response.
+func (ctx *Context) AbortWith(status int, body string) {
+	ctx.filterAction = FilterAbort
+	ctx.aborted = true
+	if status > 0 {
+		ctx.Output.SetStatus(status)
+		ctx.Output.Body([]byte(body))
+	}
+}
+
+// SkipFilters skips the remaining filters of the position in a filter,
+// the request continues with the router.
+func (ctx *Context) SkipFilters() {
+	ctx.filterAction = FilterSkip
+}
+
+// Next continues the remaining filters of the position in a filter, even if the response is written,
+// a controller doesn't run once the response is written.
+func (ctx *Context) Next() {
+	ctx.filterAction = FilterNext
+}
+
+// IsAborted returns whether a filter stopped the request with AbortWith.
+func (ctx *Context) IsAborted() bool {
+	return ctx.aborted
+}
+
+// FilterAction returns the action set by the last filter.
+func (ctx *Context) FilterAction() FilterAction {
+	return ctx.filterAction
+}
+
+// SetFilterAction sets the action of the filter, the router resets it to FilterDefault before each filter.
+func (ctx *Context) SetFilterAction(action FilterAction) {
+	ctx.filterAction = action
+}
+
 // WriteString Write string to response body.
 // it sends response body.
 func (ctx *Context) WriteString(content string) {
@@ -177,20 +271,25 @@ func (ctx *Context) CheckXSRFCookie() bool {
 //started set to true if response was written to then don't execute other handler
 type Response struct {
 	http.ResponseWriter
-	Started bool
-	Status  int
+	Started  bool
+	Status   int
+	hijacked bool
 }
 
 func (r *Response) reset(rw http.ResponseWriter) {
 	r.ResponseWriter = rw
 	r.Status = 0
 	r.Started = false
+	r.hijacked = false
 }
 
 // Write writes the data to the connection as part of an HTTP reply,
 // and sets `started` to true.
 // started means the response has sent out.
 func (r *Response) Write(p []byte) (int, error) {
+	if r.hijacked {
+		return 0, http.ErrHijacked
+	}
 	r.Started = true
 	return r.ResponseWriter.Write(p)
 }
@@ -206,7 +305,7 @@ func (r *Response) Copy(buf *bytes.Buffer) (int64, error) {
 // WriteHeader sends an HTTP response header with status code,
 // and sets `started` to true.
 func (r *Response) WriteHeader(code int) {
-	if r.Status > 0 {
+	if r.Status > 0 || r.hijacked {
 		//prevent multiple response.WriteHeader calls
 		return
 	}
@@ -216,21 +315,44 @@ func (r *Response) WriteHeader(code int) {
 }
 
 // Hijack hijacker for http
+// once the connection is hijacked, the response is started and
+// the writes of goweb, eg. the status of ServeHTTP, are dropped.
 func (r *Response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	hj, ok := r.ResponseWriter.(http.Hijacker)
 	if !ok {
 		return nil, nil, errors.New("webserver doesn't support hijacking")
 	}
-	return hj.Hijack()
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		r.hijacked = true
+		r.Started = true
+	}
+	return conn, rw, err
+}
+
+// Hijacked returns whether the connection is hijacked.
+func (r *Response) Hijacked() bool {
+	return r.hijacked
 }
 
 // Flush http.Flusher
+// it sends the header with status 200 if no status is written.
 func (r *Response) Flush() {
-	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok && !r.hijacked {
+		if r.Status == 0 {
+			r.Status = http.StatusOK
+		}
+		r.Started = true
 		f.Flush()
 	}
 }
 
+// Unwrap returns the http.ResponseWriter wrapped by Response,
+// so that http.ResponseController reaches its features.
+func (r *Response) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // CloseNotify http.CloseNotifier
 func (r *Response) CloseNotify() <-chan bool {
 	if cn, ok := r.ResponseWriter.(http.CloseNotifier); ok {
@@ -24,6 +24,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cooleo/goweb/session"
 )
@@ -35,6 +36,9 @@ var (
 	acceptsXMLRegex  = regexp.MustCompile(`(application/xml|text/xml)(?:,|$)`)
 	acceptsJSONRegex = regexp.MustCompile(`(application/json)(?:,|$)`)
 	maxParam         = 50
+	timeType         = reflect.TypeOf(time.Time{})
+	// the layouts of the time.Time bound from the request
+	timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
 )
 
 // gowebInput operates the http request header, data, cookie and body.
@@ -285,6 +289,13 @@ func (input *gowebInput) Params() map[string]string {
 	return m
 }
 
+// ResetParams clears all the router params.
+// it's used when probing several router trees for the same request.
+func (input *gowebInput) ResetParams() {
+	input.pnames = input.pnames[:0]
+	input.pvalues = input.pvalues[:0]
+}
+
 // SetParam will set the param with key and value
 func (input *gowebInput) SetParam(key, val string) {
 	// check if already exists
@@ -397,6 +408,9 @@ func (input *gowebInput) Bind(dest interface{}, key string) error {
 	if !value.CanSet() {
 		return errors.New("goweb: non-settable variable passed to Bind: " + key)
 	}
+	if input.Context.Request.Form == nil {
+		input.Context.Request.ParseForm()
+	}
 	rv := input.bind(key, value.Type())
 	if !rv.IsValid() {
 		return errors.New("goweb: reflect value is empty")
@@ -405,6 +419,29 @@ func (input *gowebInput) Bind(dest interface{}, key string) error {
 	return nil
 }
 
+// BindHeader binds the request header key to dest like Bind.
+// var id int  gowebInput.BindHeader(&id, "X-Request-Id")
+func (input *gowebInput) BindHeader(dest interface{}, key string) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr {
+		return errors.New("goweb: non-pointer passed to BindHeader: " + key)
+	}
+	value = value.Elem()
+	if !value.CanSet() {
+		return errors.New("goweb: non-settable variable passed to BindHeader: " + key)
+	}
+	val := input.Header(key)
+	if len(val) == 0 {
+		return nil
+	}
+	rv := input.bindValue(val, value.Type())
+	if !rv.IsValid() {
+		return errors.New("goweb: reflect value is empty")
+	}
+	value.Set(rv)
+	return nil
+}
+
 func (input *gowebInput) bind(key string, typ reflect.Type) reflect.Value {
 	rv := reflect.Zero(typ)
 	switch typ.Kind() {
@@ -441,6 +478,14 @@ func (input *gowebInput) bind(key string, typ reflect.Type) reflect.Value {
 	case reflect.Slice:
 		rv = input.bindSlice(&input.Context.Request.Form, key, typ)
 	case reflect.Struct:
+		if typ == timeType {
+			val := input.Query(key)
+			if len(val) == 0 {
+				return rv
+			}
+			rv = input.bindTime(val, typ)
+			break
+		}
 		rv = input.bindStruct(&input.Context.Request.Form, key, typ)
 	case reflect.Ptr:
 		rv = input.bindPoint(key, typ)
@@ -466,6 +511,10 @@ func (input *gowebInput) bindValue(val string, typ reflect.Type) reflect.Value {
 	case reflect.Slice:
 		rv = input.bindSlice(&url.Values{"": {val}}, "", typ)
 	case reflect.Struct:
+		if typ == timeType {
+			rv = input.bindTime(val, typ)
+			break
+		}
 		rv = input.bindStruct(&url.Values{"": {val}}, "", typ)
 	case reflect.Ptr:
 		rv = input.bindPoint(val, typ)
@@ -506,16 +555,29 @@ func (input *gowebInput) bindFloat(val string, typ reflect.Type) reflect.Value {
 }
 
 func (input *gowebInput) bindString(val string, typ reflect.Type) reflect.Value {
-	return reflect.ValueOf(val)
+	pValue := reflect.New(typ)
+	pValue.Elem().SetString(val)
+	return pValue.Elem()
 }
 
 func (input *gowebInput) bindBool(val string, typ reflect.Type) reflect.Value {
-	val = strings.TrimSpace(strings.ToLower(val))
-	switch val {
+	pValue := reflect.New(typ)
+	switch strings.TrimSpace(strings.ToLower(val)) {
 	case "true", "on", "1":
-		return reflect.ValueOf(true)
+		pValue.Elem().SetBool(true)
+	}
+	return pValue.Elem()
+}
+
+func (input *gowebInput) bindTime(val string, typ reflect.Type) reflect.Value {
+	pValue := reflect.New(typ)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, val); err == nil {
+			pValue.Elem().Set(reflect.ValueOf(t))
+			break
+		}
 	}
-	return reflect.ValueOf(false)
+	return pValue.Elem()
 }
 
 type sliceValue struct {
@@ -171,3 +171,44 @@ func TestParams(t *testing.T) {
 	}
 
 }
+
+func TestResetParams(t *testing.T) {
+	inp := NewInput()
+	inp.SetParam("p1", "val1_ver1")
+	inp.SetParam("p2", "val2_ver1")
+	inp.ResetParams()
+	if inp.ParamsLen() != 0 || inp.Param("p1") != "" {
+		t.Fatal("ResetParams should clear all the params")
+	}
+	inp.SetParam("p1", "val1_ver2")
+	if inp.Param("p1") != "val1_ver2" {
+		t.Fatal("Param should be set after ResetParams")
+	}
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "text/html"}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html"},
+		{"application/xml;q=0.5, application/json;q=0.4", "application/xml"},
+		{"text/*;q=0.3, text/html;q=0.7, */*;q=0.1", "text/html"},
+		{"application/*, application/json;q=0", "application/xml"},
+		{"*/*;q=0.5, application/json;q=0", "application/xml"},
+		{"image/png", ""},
+		{"text/html;level=1", ""},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", tt.accept)
+		ctx := NewContext()
+		ctx.Reset(httptest.NewRecorder(), r)
+		if got := ctx.Input.NegotiateContentType(offers...); got != tt.want {
+			t.Errorf("Accept %q should negotiate %q, get %q", tt.accept, tt.want, got)
+		}
+	}
+}
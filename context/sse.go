@@ -0,0 +1,178 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a Server-Sent Event.
+// Data is sent as is if it's a string or []byte, otherwise it's encoded as json.
+// the empty fields are not sent.
+type Event struct {
+	ID    string
+	Event string
+	Retry time.Duration
+	Data  interface{}
+}
+
+// EventStream writes Server-Sent Events to the response.
+// it isn't safe for concurrent use, send the events from one goroutine,
+// eg. with Serve.
+type EventStream struct {
+	ctx *Context
+}
+
+var eventFieldReplacer = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// EventStream starts a text/event-stream response and returns the EventStream writing to it.
+// the response isn't compressed or buffered, and goweb doesn't write it any more after the router.
+// it returns an error if the response is started or the webserver doesn't support flushing.
+// usage:
+//	es, err := ctx.Output.EventStream()
+//	if err != nil {
+//		return
+//	}
+//	es.Send(&context.Event{ID: "1", Event: "stats", Data: stats})
+func (output *gowebOutput) EventStream() (*EventStream, error) {
+	rw := output.Context.ResponseWriter
+	if rw.Started {
+		return nil, errors.New("goweb: the response is already started")
+	}
+	if _, ok := rw.ResponseWriter.(http.Flusher); !ok {
+		return nil, errors.New("goweb: webserver doesn't support flushing")
+	}
+	output.EnableGzip = false
+	header := rw.Header()
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// disables the proxy buffering of nginx
+	header.Set("X-Accel-Buffering", "no")
+	status := output.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	rw.WriteHeader(status)
+	output.Status = 0
+	rw.Flush()
+	return &EventStream{ctx: output.Context}, nil
+}
+
+// LastEventID returns the Last-Event-ID header sent by the reconnecting client,
+// the stream should resume after it.
+func (es *EventStream) LastEventID() string {
+	return es.ctx.Input.Header("Last-Event-ID")
+}
+
+// Done returns a channel which is closed when the client disconnects.
+func (es *EventStream) Done() <-chan struct{} {
+	return es.ctx.Context().Done()
+}
+
+// Closed returns whether the client disconnected.
+func (es *EventStream) Closed() bool {
+	return es.ctx.Context().Err() != nil
+}
+
+// Send writes the event and flushes it to the client.
+func (es *EventStream) Send(e *Event) error {
+	var buf bytes.Buffer
+	if e.ID != "" {
+		buf.WriteString("id: " + eventFieldReplacer.Replace(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + eventFieldReplacer.Replace(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(int64(e.Retry/time.Millisecond), 10) + "\n")
+	}
+	if e.Data != nil {
+		var data []byte
+		switch d := e.Data.(type) {
+		case string:
+			data = []byte(d)
+		case []byte:
+			data = d
+		default:
+			var err error
+			if data, err = json.Marshal(d); err != nil {
+				return err
+			}
+		}
+		data = bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			buf.WriteString("data: ")
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteByte('\n')
+	return es.write(buf.Bytes())
+}
+
+// Heartbeat writes a comment line, which keeps the connection alive through the proxies.
+func (es *EventStream) Heartbeat() error {
+	return es.write([]byte(":\n\n"))
+}
+
+func (es *EventStream) write(p []byte) error {
+	if es.Closed() {
+		return es.ctx.Context().Err()
+	}
+	if _, err := es.ctx.ResponseWriter.Write(p); err != nil {
+		return err
+	}
+	es.ctx.ResponseWriter.Flush()
+	return nil
+}
+
+// Serve sends the events of events and a heartbeat every interval,
+// until events is closed or the client disconnects.
+// interval 0 disables the heartbeat.
+// it returns nil when events is closed.
+func (es *EventStream) Serve(events <-chan *Event, interval time.Duration) error {
+	var heartbeat <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		select {
+		case <-es.Done():
+			return es.ctx.Context().Err()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := es.Send(e); err != nil {
+				return err
+			}
+		case <-heartbeat:
+			if err := es.Heartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
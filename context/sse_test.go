@@ -0,0 +1,73 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	gocontext "context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEventStream(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/events", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Last-Event-ID", "7")
+	w := httptest.NewRecorder()
+	ctx := NewContext()
+	ctx.Reset(w, r)
+	ctx.Output.EnableGzip = true
+
+	es, err := ctx.Output.EventStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan *Event, 2)
+	events <- &Event{ID: "8", Event: "stats", Retry: 3 * time.Second, Data: "a\nb"}
+	events <- &Event{Data: map[string]int{"after": 7}}
+	close(events)
+	if err := es.Serve(events, time.Minute); err != nil {
+		t.Error(err)
+	}
+	if es.LastEventID() != "7" {
+		t.Errorf("LastEventID should be 7, get %s", es.LastEventID())
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream; charset=utf-8" || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("the event stream shouldn't be compressed, get header %v", w.Header())
+	}
+	if !w.Flushed {
+		t.Error("the event stream should be flushed")
+	}
+	expected := "id: 8\nevent: stats\nretry: 3000\ndata: a\ndata: b\n\ndata: {\"after\":7}\n\n"
+	if w.Body.String() != expected {
+		t.Errorf("the event stream should be %q, get %q", expected, w.Body.String())
+	}
+	if _, err := ctx.Output.EventStream(); err == nil {
+		t.Error("the started response should not start an event stream")
+	}
+
+	c, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	r, _ = http.NewRequest("GET", "/closed", nil)
+	ctx.Reset(httptest.NewRecorder(), r.WithContext(c))
+	es, _ = ctx.Output.EventStream()
+	if err := es.Serve(make(chan *Event), 0); err != gocontext.Canceled {
+		t.Errorf("Serve should return when the client disconnects, get %v", err)
+	}
+	if !es.Closed() {
+		t.Error("the event stream should be closed")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// the streamed response is flushed to the client
+// when StreamFlushSize bytes are written or StreamFlushInterval elapses since the last flush.
+var (
+	StreamFlushSize     = 32 * 1024
+	StreamFlushInterval = time.Second
+)
+
+type flusher interface {
+	Flush() error
+}
+
+// streamWriter compresses the written data by the accept encoder
+// and flushes it periodically.
+type streamWriter struct {
+	rw        *Response
+	encoder   resetWriter
+	size      int
+	lastFlush time.Time
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	n, err := s.encoder.Write(p)
+	if err != nil {
+		return n, err
+	}
+	s.size += n
+	if s.size >= StreamFlushSize || time.Since(s.lastFlush) >= StreamFlushInterval {
+		err = s.flush()
+	}
+	return n, err
+}
+
+// Flush http.Flusher
+// it sends the written data to the client.
+func (s *streamWriter) Flush() {
+	s.flush()
+}
+
+func (s *streamWriter) flush() error {
+	if f, ok := s.encoder.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	s.rw.Flush()
+	s.size = 0
+	s.lastFlush = time.Now()
+	return nil
+}
+
+// Stream sends the response written by fn without buffering it.
+// if EnableGzip, the data is compressed by the Accept-Encoding of the request.
+// the written data is flushed periodically and when fn returns,
+// w is a http.Flusher to flush it at once.
+// the error of fn is returned, the response is already started then.
+// usage:
+//	ctx.Output.Stream("text/csv; charset=utf-8", func(w io.Writer) error {
+//		cw := csv.NewWriter(w)
+//		for _, r := range records {
+//			cw.Write(r)
+//		}
+//		cw.Flush()
+//		return cw.Error()
+//	})
+func (output *gowebOutput) Stream(contentType string, fn func(w io.Writer) error) error {
+	rw := output.Context.ResponseWriter
+	if rw.Started {
+		return errors.New("goweb: the response is already started")
+	}
+	var encoding string
+	if output.EnableGzip {
+		encoding = ParseEncoding(output.Context.Request)
+	}
+	ce := noneCompressEncoder
+	if cf, ok := encoderMap[encoding]; ok {
+		ce = cf
+	}
+	header := rw.Header()
+	header.Del("Content-Length")
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if ce.name != "" {
+		header.Set("Content-Encoding", ce.name)
+		header.Add("Vary", "Accept-Encoding")
+	}
+	status := output.Status
+	if status == 0 {
+		status = 200
+	}
+	rw.WriteHeader(status)
+	output.Status = 0
+	encoder := ce.encode(rw, flate.BestSpeed)
+	defer ce.put(encoder, flate.BestSpeed)
+	sw := &streamWriter{rw: rw, encoder: encoder, lastFlush: time.Now()}
+	err := fn(sw)
+	if c, ok := encoder.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	rw.Flush()
+	return err
+}
+
+// JSONArrayEncoder writes a json array to the writer element by element,
+// so that a large array needn't be built in memory.
+type JSONArrayEncoder struct {
+	w      io.Writer
+	count  int
+	closed bool
+}
+
+// NewJSONArrayEncoder returns a JSONArrayEncoder writing to w.
+func NewJSONArrayEncoder(w io.Writer) *JSONArrayEncoder {
+	return &JSONArrayEncoder{w: w}
+}
+
+// Encode writes v as the next element of the array.
+func (e *JSONArrayEncoder) Encode(v interface{}) error {
+	if e.closed {
+		return errors.New("goweb: the json array is closed")
+	}
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sep := []byte{','}
+	if e.count == 0 {
+		sep[0] = '['
+	}
+	if _, err = e.w.Write(sep); err != nil {
+		return err
+	}
+	e.count++
+	_, err = e.w.Write(content)
+	return err
+}
+
+// Close ends the array, an empty array is written if there is no element.
+func (e *JSONArrayEncoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	end := "]"
+	if e.count == 0 {
+		end = "[]"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// JSONArray streams a json array of the elements returned by next,
+// until next returns io.EOF.
+// usage, streaming the rows of an orm query batch by batch:
+//	it := orm.NewQueryIterator(o.QueryTable("user").OrderBy("Id"), &[]*User{}, 500)
+//	ctx.Output.JSONArray(it.Next)
+func (output *gowebOutput) JSONArray(next func() (interface{}, error)) error {
+	return output.Stream("application/json; charset=utf-8", func(w io.Writer) error {
+		enc := NewJSONArrayEncoder(w)
+		for {
+			v, err := next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if err = enc.Encode(v); err != nil {
+				return err
+			}
+		}
+		return enc.Close()
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamOutput(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/users", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	ctx := NewContext()
+	ctx.Reset(w, r)
+	ctx.Output.EnableGzip = true
+
+	i := 0
+	err := ctx.Output.JSONArray(func() (interface{}, error) {
+		if i == 3 {
+			return nil, io.EOF
+		}
+		i++
+		return map[string]int{"id": i}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" || !w.Flushed {
+		t.Fatalf("the stream should be gzipped and flushed, get header %v", w.Header())
+	}
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(gr)
+	if string(body) != `[{"id":1},{"id":2},{"id":3}]` {
+		t.Errorf("unexpected json array %s", body)
+	}
+	if err := ctx.Output.Stream("text/plain", nil); err == nil {
+		t.Error("the started response should not be streamed")
+	}
+
+	r, _ = http.NewRequest("GET", "/empty", nil)
+	w = httptest.NewRecorder()
+	ctx.Reset(w, r)
+	ctx.Output.JSONArray(func() (interface{}, error) {
+		return nil, io.EOF
+	})
+	if w.Body.String() != "[]" || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("the empty stream should be [] without encoding, get %s", w.Body.String())
+	}
+}
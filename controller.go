@@ -16,6 +16,7 @@ package goweb
 
 import (
 	"bytes"
+	gocontext "context"
 	"errors"
 	"html/template"
 	"io"
@@ -180,9 +181,22 @@ func (c *Controller) Render() error {
 	if !c.EnableRender {
 		return nil
 	}
-	rb, err := c.RenderBytes()
-	if err != nil {
-		return err
+	liveReload := BConfig.RunMode == DEV && BConfig.WebConfig.EnableLiveReload && templateWatch != nil
+	var rb []byte
+	if liveReload {
+		// the page of the templates failing to build shows their errors until they are fixed
+		if rb = templateErrorPage(c.renderFiles()); rb != nil {
+			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		}
+	}
+	if rb == nil {
+		var err error
+		if rb, err = c.RenderBytes(); err != nil {
+			return err
+		}
+		if liveReload {
+			rb = injectLiveReload(rb)
+		}
 	}
 	c.Ctx.Output.Header("Content-Type", "text/html; charset=utf-8")
 	return c.Ctx.Output.Body(rb)
@@ -224,25 +238,29 @@ func (c *Controller) RenderBytes() ([]byte, error) {
 
 func (c *Controller) renderTemplate() (bytes.Buffer, error) {
 	var buf bytes.Buffer
+	buildFiles := c.renderFiles()
+	// the template watcher rebuilds the changed templates, otherwise they are rebuilt at every render
+	if BConfig.RunMode == DEV && templateWatch == nil {
+		BuildTemplate(BConfig.WebConfig.ViewsPath, buildFiles...)
+	}
+	return buf, executeTemplate(&buf, c.TplName, c.Data)
+}
+
+// renderFiles returns the template, layout and LayoutSections files of the render.
+func (c *Controller) renderFiles() []string {
 	if c.TplName == "" {
 		c.TplName = strings.ToLower(c.controllerName) + "/" + strings.ToLower(c.actionName) + "." + c.TplExt
 	}
-	if BConfig.RunMode == DEV {
-		buildFiles := []string{c.TplName}
-		if c.Layout != "" {
-			buildFiles = append(buildFiles, c.Layout)
-			if c.LayoutSections != nil {
-				for _, sectionTpl := range c.LayoutSections {
-					if sectionTpl == "" {
-						continue
-					}
-					buildFiles = append(buildFiles, sectionTpl)
-				}
+	files := []string{c.TplName}
+	if c.Layout != "" {
+		files = append(files, c.Layout)
+		for _, sectionTpl := range c.LayoutSections {
+			if sectionTpl != "" {
+				files = append(files, sectionTpl)
 			}
 		}
-		BuildTemplate(BConfig.WebConfig.ViewsPath, buildFiles...)
 	}
-	return buf, executeTemplate(&buf, c.TplName, c.Data)
+	return files
 }
 
 // Redirect sends the redirection response to url with status code.
@@ -266,6 +284,10 @@ func (c *Controller) CustomAbort(status int, body string) {
 	if _, ok := ErrorMaps[body]; ok {
 		panic(body)
 	}
+	// the user string is the detail of the problem in the problem error mode
+	if problemMode(c.Ctx) {
+		c.AbortProblem(NewProblem(status, body))
+	}
 	// last panic user string
 	c.Ctx.ResponseWriter.Write([]byte(body))
 	panic(ErrAbort)
@@ -276,6 +298,12 @@ func (c *Controller) StopRun() {
 	panic(ErrAbort)
 }
 
+// Context returns the context.Context of the request,
+// which is cancelled when the client disconnects or the request is finished.
+func (c *Controller) Context() gocontext.Context {
+	return c.Ctx.Context()
+}
+
 // URLFor does another controller handler in this request function.
 // it goes to this controller method if endpoint is not clear.
 func (c *Controller) URLFor(endpoint string, values ...interface{}) string {
@@ -303,6 +331,17 @@ func (c *Controller) ServeJSON(encoding ...bool) {
 	c.Ctx.Output.JSON(c.Data["json"], hasIndent, hasEncoding)
 }
 
+// ServeJSONArray streams a json array of the elements returned by next until io.EOF,
+// without building the array in memory and disables the template rendering.
+// the rows of an orm query are streamed by the Next of an orm.QueryIterator:
+//	it := orm.NewQueryIterator(o.QueryTable("user").OrderBy("Id"), &[]*User{}, 500)
+//	c.ServeJSONArray(it.Next)
+// refer: context.gowebOutput.JSONArray
+func (c *Controller) ServeJSONArray(next func() (interface{}, error)) error {
+	c.EnableRender = false
+	return c.Ctx.Output.JSONArray(next)
+}
+
 // ServeJSONP sends a jsonp response.
 func (c *Controller) ServeJSONP() {
 	hasIndent := true
@@ -322,6 +361,7 @@ func (c *Controller) ServeXML() {
 }
 
 // ServeFormatted serve Xml OR Json, depending on the value of the Accept header
+// refer: Controller.Respond for the content negotiation.
 func (c *Controller) ServeFormatted() {
 	accept := c.Ctx.Input.Header("Accept")
 	switch accept {
@@ -334,6 +374,21 @@ func (c *Controller) ServeFormatted() {
 	}
 }
 
+// EventStream starts a Server-Sent Events response and disables the template rendering.
+// refer: context.gowebOutput.EventStream
+// usage:
+//	func (c *StatsController) Get() {
+//		es, err := c.EventStream()
+//		if err != nil {
+//			c.Abort("500")
+//		}
+//		es.Serve(stats.Subscribe(es.LastEventID()), 15*time.Second)
+//	}
+func (c *Controller) EventStream() (*context.EventStream, error) {
+	c.EnableRender = false
+	return c.Ctx.Output.EventStream()
+}
+
 // Input returns the input data map from POST or PUT request body and query string.
 func (c *Controller) Input() url.Values {
 	if c.Ctx.Request.Form == nil {
@@ -347,6 +402,28 @@ func (c *Controller) ParseForm(obj interface{}) error {
 	return ParseForm(c.Input(), obj)
 }
 
+// BindBody decodes the request body into dst by its Content-Type, with BConfig.MaxMemory,
+// and runs validation.Valid on dst if valid is true.
+// the errors are *context.BindError with the status to respond, 400, 413 or 415.
+// refer: context.gowebInput.BindBody
+// usage:
+//	var u User
+//	if err := c.BindBody(&u, true); err != nil {
+//		c.Ctx.Output.SetStatus(err.(*context.BindError).Status)
+//		c.Data["json"] = err
+//		c.ServeJSON()
+//		return
+//	}
+func (c *Controller) BindBody(dst interface{}, valid ...bool) error {
+	if err := c.Ctx.Input.BindBody(dst, BConfig.MaxMemory); err != nil {
+		return err
+	}
+	if len(valid) > 0 && valid[0] {
+		return validBody(dst)
+	}
+	return nil
+}
+
 // GetString returns the input value by key string or the default value while it's present and input is blank
 func (c *Controller) GetString(key string, def ...string) string {
 	if v := c.Ctx.Input.Query(key); v != "" {
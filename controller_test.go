@@ -15,6 +15,10 @@
 package goweb
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/cooleo/goweb/context"
@@ -75,3 +79,52 @@ func TestGetInt64(t *testing.T) {
 		t.Errorf("TestGeetInt64 expect 40,get %T,%v", val, val)
 	}
 }
+
+type BindController struct {
+	Controller
+}
+
+type bindUser struct {
+	Name string `json:"name" xml:"name" form:"name" valid:"Required"`
+	Age  int    `json:"age" xml:"age" form:"age" valid:"Range(1, 140)"`
+}
+
+func (b *BindController) Post() {
+	var u bindUser
+	if err := b.BindBody(&u, true); err != nil {
+		be := err.(*context.BindError)
+		b.Ctx.Output.SetStatus(be.Status)
+		b.Ctx.Output.JSON(be, false, false)
+		return
+	}
+	b.Ctx.Output.Body([]byte(fmt.Sprintf("%s:%d", u.Name, u.Age)))
+}
+
+func TestBindBody(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/bind", &BindController{})
+
+	maxMemory := BConfig.MaxMemory
+	BConfig.MaxMemory = 64
+	defer func() { BConfig.MaxMemory = maxMemory }()
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+		response    string
+	}{
+		{"application/json; charset=utf-8", `{"name":"cooleo","age":7}`, 200, "cooleo:7"},
+		{"application/x-www-form-urlencoded", "name=form&age=10", 200, "form:10"},
+		{"application/json", `{"name":"","age":200}`, 400, `"fields":{"Age":"Range is 1 to 140","Name":"Can not be empty"}`},
+		{"application/json", `{"name":"` + strings.Repeat("x", 64) + `"}`, 413, `"status":413`},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("POST", "/bind", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.response) {
+			t.Errorf("%s %s should get %d %s, get %d %s", tt.contentType, tt.body, tt.status, tt.response, w.Code, w.Body.String())
+		}
+	}
+}
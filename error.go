@@ -208,6 +208,22 @@ type errorInfo struct {
 // there is 10 kinds default error(40x and 50x)
 var ErrorMaps = make(map[string]*errorInfo, 10)
 
+// show 400 bad request error.
+func badRequest(rw http.ResponseWriter, r *http.Request) {
+	t, _ := template.New("goweberrortemp").Parse(errtpl)
+	data := map[string]interface{}{
+		"Title":        http.StatusText(400),
+		"gowebVersion": VERSION,
+	}
+	data["Content"] = template.HTML("<br>The request you have sent can't be understood." +
+		"<br>Perhaps you are here because:" +
+		"<br><br><ul>" +
+		"<br>The request params are malformed" +
+		"<br>The request body is malformed" +
+		"</ul>")
+	t.Execute(rw, data)
+}
+
 // show 401 unauthorized error.
 func unauthorized(rw http.ResponseWriter, r *http.Request) {
 	t, _ := template.New("goweberrortemp").Parse(errtpl)
@@ -291,6 +307,51 @@ func methodNotAllowed(rw http.ResponseWriter, r *http.Request) {
 	t.Execute(rw, data)
 }
 
+// show 406 Not Acceptable
+func notAcceptable(rw http.ResponseWriter, r *http.Request) {
+	t, _ := template.New("goweberrortemp").Parse(errtpl)
+	data := map[string]interface{}{
+		"Title":        http.StatusText(406),
+		"gowebVersion": VERSION,
+	}
+	data["Content"] = template.HTML("<br>The page you have requested can't be served in an acceptable format." +
+		"<br>Perhaps you are here because:" +
+		"<br><br><ul>" +
+		"<br>The Accept header of the request doesn't match any format of the page" +
+		"</ul>")
+	t.Execute(rw, data)
+}
+
+// show 413 Request Entity Too Large
+func requestEntityTooLarge(rw http.ResponseWriter, r *http.Request) {
+	t, _ := template.New("goweberrortemp").Parse(errtpl)
+	data := map[string]interface{}{
+		"Title":        http.StatusText(413),
+		"gowebVersion": VERSION,
+	}
+	data["Content"] = template.HTML("<br>The request you have sent is too large." +
+		"<br>Perhaps you are here because:" +
+		"<br><br><ul>" +
+		"<br>The request body is larger than the server accepts" +
+		"</ul>")
+	t.Execute(rw, data)
+}
+
+// show 415 Unsupported Media Type
+func unsupportedMediaType(rw http.ResponseWriter, r *http.Request) {
+	t, _ := template.New("goweberrortemp").Parse(errtpl)
+	data := map[string]interface{}{
+		"Title":        http.StatusText(415),
+		"gowebVersion": VERSION,
+	}
+	data["Content"] = template.HTML("<br>The request you have sent is in an unsupported format." +
+		"<br>Perhaps you are here because:" +
+		"<br><br><ul>" +
+		"<br>The Content-Type header of the request is missing or unknown" +
+		"</ul>")
+	t.Execute(rw, data)
+}
+
 // show 500 internal server error.
 func internalServerError(rw http.ResponseWriter, r *http.Request) {
 	t, _ := template.New("goweberrortemp").Parse(errtpl)
@@ -410,6 +471,10 @@ func exception(errCode string, ctx *context.Context) {
 		}
 		return 503
 	}
+	if problemMode(ctx) {
+		writeProblem(ctx, NewProblem(atoi(errCode), ""))
+		return
+	}
 
 	for _, ec := range []string{errCode, "503", "500"} {
 		if h, ok := ErrorMaps[ec]; ok {
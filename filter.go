@@ -14,11 +14,29 @@
 
 package goweb
 
-import "github.com/cooleo/goweb/context"
+import (
+	"github.com/cooleo/goweb/context"
+	"github.com/cooleo/goweb/utils"
+)
 
 // FilterFunc defines a filter function which is invoked before the controller handler is executed.
 type FilterFunc func(*context.Context)
 
+// FilterChain wraps the FilterFunc of a single route and returns a new one.
+// Chains are composed in declared order, the first one being the outermost,
+// and a chain can short-circuit the route by not calling next.
+// usage:
+//    func Auth(next goweb.FilterFunc) goweb.FilterFunc {
+//        return func(ctx *context.Context) {
+//            if ctx.Input.Session("uid") == nil {
+//                ctx.Redirect(302, "/login")
+//                return
+//            }
+//            next(ctx)
+//        }
+//    }
+type FilterChain func(next FilterFunc) FilterFunc
+
 // FilterRouter defines a filter operation which is invoked before the controller handler is executed.
 // It can match the URL against a pattern, and execute a filter function
 // when a request with a matching URL arrives.
@@ -27,6 +45,73 @@ type FilterRouter struct {
 	tree           *Tree
 	pattern        string
 	returnOnOutput bool
+	name           string
+	priority       int
+	// the Namespace filter this one is added from
+	origin *FilterRouter
+}
+
+// FilterOption sets the name, priority or returnOnOutput of a filter
+// inserted by InsertFilterWithOptions.
+type FilterOption func(*FilterRouter)
+
+// FilterName names the filter, so that it can be removed by RemoveFilter.
+// the name should be unique in the ControllerRegister.
+func FilterName(name string) FilterOption {
+	return func(f *FilterRouter) {
+		f.name = name
+	}
+}
+
+// FilterPriority orders the filters of the same position,
+// the smaller priority runs first, and the default one is 0.
+// the filters of the same priority run in insertion order.
+func FilterPriority(priority int) FilterOption {
+	return func(f *FilterRouter) {
+		f.priority = priority
+	}
+}
+
+// FilterReturnOnOutput sets whether the filters after this one are skipped
+// once the response is written, it's true by default.
+func FilterReturnOnOutput(returnOnOutput bool) FilterOption {
+	return func(f *FilterRouter) {
+		f.returnOnOutput = returnOnOutput
+	}
+}
+
+// FilterInfo describes an inserted filter.
+type FilterInfo struct {
+	// Name is the name set by FilterName, or the name of the FilterFunc.
+	Name           string
+	Pattern        string
+	Priority       int
+	ReturnOnOutput bool
+}
+
+// filterPositions are the names of the filter positions in execution order.
+var filterPositions = []struct {
+	pos  int
+	name string
+}{
+	{BeforeStatic, "Before Static"},
+	{BeforeRouter, "Before Router"},
+	{BeforeExec, "Before Exec"},
+	{AfterExec, "After Exec"},
+	{FinishRouter, "Finish Router"},
+}
+
+func (f *FilterRouter) info() FilterInfo {
+	name := f.name
+	if name == "" {
+		name = utils.GetFuncName(f.filterFunc)
+	}
+	return FilterInfo{
+		Name:           name,
+		Pattern:        f.pattern,
+		Priority:       f.priority,
+		ReturnOnOutput: f.returnOnOutput,
+	}
 }
 
 // ValidRouter checks if the current request is matched by this filter.
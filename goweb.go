@@ -54,24 +54,41 @@ func Run(params ...string) {
 	initBeforeHTTPRun()
 
 	if len(params) > 0 && params[0] != "" {
-		strs := strings.Split(params[0], ":")
-		if len(strs) > 0 && strs[0] != "" {
-			BConfig.Listen.HTTPAddr = strs[0]
-		}
-		if len(strs) > 1 && strs[1] != "" {
-			BConfig.Listen.HTTPPort, _ = strconv.Atoi(strs[1])
-		}
+		setListenAddr(params[0])
 	}
 
 	BeeApp.Run()
 }
 
+// RunWithMiddleWares Run goweb application with the ControllerRegister wrapped by mws.
+// goweb.RunWithMiddleWares(":8089", gziphandler.GzipHandler)
+func RunWithMiddleWares(addr string, mws ...MiddleWare) {
+	initBeforeHTTPRun()
+
+	if addr != "" {
+		setListenAddr(addr)
+	}
+
+	BeeApp.Run(mws...)
+}
+
+func setListenAddr(addr string) {
+	strs := strings.Split(addr, ":")
+	if len(strs) > 0 && strs[0] != "" {
+		BConfig.Listen.HTTPAddr = strs[0]
+	}
+	if len(strs) > 1 && strs[1] != "" {
+		BConfig.Listen.HTTPPort, _ = strconv.Atoi(strs[1])
+	}
+}
+
 func initBeforeHTTPRun() {
 	//init hooks
 	AddAPPStartHook(registerMime)
 	AddAPPStartHook(registerDefaultErrorHandler)
 	AddAPPStartHook(registerSession)
 	AddAPPStartHook(registerDocs)
+	AddAPPStartHook(checkRoutes)
 	AddAPPStartHook(registerTemplate)
 	AddAPPStartHook(registerAdmin)
 
@@ -0,0 +1,223 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	beecontext "github.com/cooleo/goweb/context"
+)
+
+// RouterGroup adds routers to ControllerRegister with a common prefix and filters.
+// unlike the Namespace filters, which are inserted as prefix patterns,
+// the group filters are stored on the routers of the group,
+// so that they don't run for the sibling paths sharing the prefix, eg. /apidocs for /api.
+// as a result they only run for the requests matching a router of the group,
+// the 404 and 405 responses under the prefix skip them,
+// insert a filter with the prefix pattern for the filters that must see every request.
+type RouterGroup struct {
+	prefix   string
+	chains   []FilterChain
+	handlers *ControllerRegister
+}
+
+// GroupOption sets the filters of RouterGroup.
+type GroupOption func(*RouterGroup)
+
+// GroupBefore runs the filters before the routers of the group,
+// the router doesn't run if a filter writes the response or aborts with AbortWith.
+func GroupBefore(filters ...FilterFunc) GroupOption {
+	return func(g *RouterGroup) {
+		for _, f := range filters {
+			f := f
+			g.chains = append(g.chains, func(next FilterFunc) FilterFunc {
+				return func(ctx *beecontext.Context) {
+					f(ctx)
+					if !ctx.ResponseWriter.Started && !ctx.IsAborted() {
+						next(ctx)
+					}
+				}
+			})
+		}
+	}
+}
+
+// GroupAfter runs the filters after the routers of the group.
+func GroupAfter(filters ...FilterFunc) GroupOption {
+	return func(g *RouterGroup) {
+		for _, f := range filters {
+			f := f
+			g.chains = append(g.chains, func(next FilterFunc) FilterFunc {
+				return func(ctx *beecontext.Context) {
+					next(ctx)
+					f(ctx)
+				}
+			})
+		}
+	}
+}
+
+// GroupChain wraps the routers of the group with the FilterChain in declared order.
+func GroupChain(chains ...FilterChain) GroupOption {
+	return func(g *RouterGroup) {
+		g.chains = append(g.chains, chains...)
+	}
+}
+
+// Group returns a RouterGroup adding routers to ControllerRegister under prefix.
+// the options apply in order, the first one being the outermost.
+// usage:
+//	api := Group("/api", GroupBefore(Auth), GroupAfter(Log))
+//	api.Router("/user", &UserController{})
+//	api.Get("/version", func(ctx *context.Context){
+//		ctx.Output.Body([]byte("1.0"))
+//	})
+func (p *ControllerRegister) Group(prefix string, opts ...GroupOption) *RouterGroup {
+	g := &RouterGroup{prefix: strings.TrimRight(prefix, "/"), handlers: p}
+	for _, o := range opts {
+		o(g)
+	}
+	return g
+}
+
+// Group returns a RouterGroup nested in the group,
+// whose routers run through the filters of the both.
+func (g *RouterGroup) Group(prefix string, opts ...GroupOption) *RouterGroup {
+	ng := g.handlers.Group(g.pattern(prefix))
+	ng.chains = g.withChains(nil)
+	for _, o := range opts {
+		o(ng)
+	}
+	return ng
+}
+
+func (g *RouterGroup) pattern(pattern string) string {
+	if pattern == "" || pattern == "/" {
+		if g.prefix == "" {
+			return "/"
+		}
+		return g.prefix
+	}
+	return g.prefix + "/" + strings.TrimLeft(pattern, "/")
+}
+
+func (g *RouterGroup) withChains(chains []FilterChain) []FilterChain {
+	all := make([]FilterChain, 0, len(g.chains)+len(chains))
+	all = append(all, g.chains...)
+	return append(all, chains...)
+}
+
+// Router same as goweb.Router
+// refer: https://godoc.org/github.com/cooleo/goweb#Router
+func (g *RouterGroup) Router(rootpath string, c ControllerInterface, mappingMethods ...string) *RouterGroup {
+	return g.RouterWithChain(rootpath, c, nil, mappingMethods...)
+}
+
+// RouterWithChain same as goweb.RouterWithChain
+// refer: https://godoc.org/github.com/cooleo/goweb#RouterWithChain
+func (g *RouterGroup) RouterWithChain(rootpath string, c ControllerInterface, chains []FilterChain, mappingMethods ...string) *RouterGroup {
+	g.handlers.AddWithChain(g.pattern(rootpath), c, g.withChains(chains), mappingMethods...)
+	return g
+}
+
+// Get same as goweb.Get
+// refer: https://godoc.org/github.com/cooleo/goweb#Get
+func (g *RouterGroup) Get(rootpath string, f FilterFunc, chains ...FilterChain) *RouterGroup {
+	g.handlers.Get(g.pattern(rootpath), f, g.withChains(chains)...)
+	return g
+}
+
+// Post same as goweb.Post
+// refer: https://godoc.org/github.com/cooleo/goweb#Post
+func (g *RouterGroup) Post(rootpath string, f FilterFunc, chains ...FilterChain) *RouterGroup {
+	g.handlers.Post(g.pattern(rootpath), f, g.withChains(chains)...)
+	return g
+}
+
+// Delete same as goweb.Delete
+// refer: https://godoc.org/github.com/cooleo/goweb#Delete
+func (g *RouterGroup) Delete(rootpath string, f FilterFunc, chains ...FilterChain) *RouterGroup {
+	g.handlers.Delete(g.pattern(rootpath), f, g.withChains(chains)...)
+	return g
+}
+
+// Put same as goweb.Put
+// refer: https://godoc.org/github.com/cooleo/goweb#Put
+func (g *RouterGroup) Put(rootpath string, f FilterFunc, chains ...FilterChain) *RouterGroup {
+	g.handlers.Put(g.pattern(rootpath), f, g.withChains(chains)...)
+	return g
+}
+
+// Head same as goweb.Head
+// refer: https://godoc.org/github.com/cooleo/goweb#Head
+func (g *RouterGroup) Head(rootpath string, f FilterFunc, chains ...FilterChain) *RouterGroup {
+	g.handlers.Head(g.pattern(rootpath), f, g.withChains(chains)...)
+	return g
+}
+
+// Options same as goweb.Options
+// refer: https://godoc.org/github.com/cooleo/goweb#Options
+func (g *RouterGroup) Options(rootpath string, f FilterFunc, chains ...FilterChain) *RouterGroup {
+	g.handlers.Options(g.pattern(rootpath), f, g.withChains(chains)...)
+	return g
+}
+
+// Patch same as goweb.Patch
+// refer: https://godoc.org/github.com/cooleo/goweb#Patch
+func (g *RouterGroup) Patch(rootpath string, f FilterFunc, chains ...FilterChain) *RouterGroup {
+	g.handlers.Patch(g.pattern(rootpath), f, g.withChains(chains)...)
+	return g
+}
+
+// Any same as goweb.Any
+// refer: https://godoc.org/github.com/cooleo/goweb#Any
+func (g *RouterGroup) Any(rootpath string, f FilterFunc, chains ...FilterChain) *RouterGroup {
+	g.handlers.Any(g.pattern(rootpath), f, g.withChains(chains)...)
+	return g
+}
+
+// Handler same as goweb.Handler
+// refer: https://godoc.org/github.com/cooleo/goweb#Handler
+func (g *RouterGroup) Handler(rootpath string, h http.Handler, options ...interface{}) *RouterGroup {
+	opts := make([]interface{}, 0, len(g.chains)+len(options))
+	for _, c := range g.chains {
+		opts = append(opts, c)
+	}
+	g.handlers.Handler(g.pattern(rootpath), h, append(opts, options...)...)
+	return g
+}
+
+// Name same as goweb.App.Name
+// refer: ControllerRegister.Name
+func (g *RouterGroup) Name(name string) *RouterGroup {
+	g.handlers.Name(name)
+	return g
+}
+
+// Params same as goweb.App.Params
+// refer: ControllerRegister.Params
+func (g *RouterGroup) Params(params ...string) *RouterGroup {
+	g.handlers.Params(params...)
+	return g
+}
+
+// Timeout same as goweb.App.Timeout
+// refer: ControllerRegister.Timeout
+func (g *RouterGroup) Timeout(d time.Duration, code ...int) *RouterGroup {
+	g.handlers.Timeout(d, code...)
+	return g
+}
@@ -20,11 +20,15 @@ func registerMime() error {
 // register default error http handlers, 404,401,403,500 and 503.
 func registerDefaultErrorHandler() error {
 	m := map[string]func(http.ResponseWriter, *http.Request){
+		"400": badRequest,
 		"401": unauthorized,
 		"402": paymentRequired,
 		"403": forbidden,
 		"404": notFound,
 		"405": methodNotAllowed,
+		"406": notAcceptable,
+		"413": requestEntityTooLarge,
+		"415": unsupportedMediaType,
 		"500": internalServerError,
 		"501": notImplemented,
 		"502": badGateway,
@@ -74,6 +78,9 @@ func registerTemplate() error {
 		}
 		return err
 	}
+	if BConfig.RunMode == DEV {
+		watchTemplates()
+	}
 	return nil
 }
 
@@ -0,0 +1,138 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"regexp"
+	"strings"
+
+	beecontext "github.com/cooleo/goweb/context"
+)
+
+// hostRouter stores the routers bound to a host pattern.
+// the host pattern is split by "." and each label can be
+// "api" -> match api only
+// ":tenant" -> match any label and set it as param :tenant
+// "*" -> match one or more labels
+type hostRouter struct {
+	pattern  string
+	labels   []string
+	params   []string
+	regexps  *regexp.Regexp
+	handlers *ControllerRegister
+}
+
+func newHostRouter(pattern string) *hostRouter {
+	h := &hostRouter{
+		pattern:  strings.ToLower(pattern),
+		handlers: NewControllerRegister(),
+	}
+	h.handlers.host = h.pattern
+	h.labels = strings.Split(h.pattern, ".")
+	reg := make([]string, 0, len(h.labels))
+	for _, l := range h.labels {
+		switch {
+		case l == "*":
+			reg = append(reg, `.+`)
+		case strings.HasPrefix(l, ":"):
+			h.params = append(h.params, l)
+			reg = append(reg, `([^.]+)`)
+		default:
+			reg = append(reg, regexp.QuoteMeta(l))
+		}
+	}
+	h.regexps = regexp.MustCompile(`^` + strings.Join(reg, `\.`) + `$`)
+	return h
+}
+
+// match checks the request host against the pattern and returns the values of the host params.
+func (h *hostRouter) match(ctx *beecontext.Context) ([]string, bool) {
+	matches := h.regexps.FindStringSubmatch(strings.ToLower(ctx.Input.Host()))
+	if matches == nil {
+		return nil, false
+	}
+	return matches[1:], true
+}
+
+// setParams sets the host params, once the router of the host matches.
+func (h *hostRouter) setParams(ctx *beecontext.Context, values []string) {
+	for i, v := range values {
+		ctx.Input.SetParam(h.params[i], v)
+	}
+}
+
+// build returns the host of the pattern with params filled in.
+// the used params are removed from params.
+// it returns false if the pattern has wildcard or a param is missing.
+func (h *hostRouter) build(params map[string]string) (string, bool) {
+	labels := make([]string, 0, len(h.labels))
+	for _, l := range h.labels {
+		if l == "*" {
+			return "", false
+		}
+		if strings.HasPrefix(l, ":") {
+			v, ok := params[l]
+			if !ok {
+				return "", false
+			}
+			l = v
+		}
+		labels = append(labels, l)
+	}
+	for _, p := range h.params {
+		delete(params, p)
+	}
+	return strings.Join(labels, "."), true
+}
+
+// Host returns the ControllerRegister whose routers are only matched
+// when the request host matches pattern.
+// routers of host patterns are matched before the host-less ones, in registration order.
+// the filters can't be inserted into it, they are inserted into p for all the hosts.
+// usage:
+//	Host("api.example.com").Add("/users", &APIUserController{})
+//	Host(":tenant.example.com").Get("/", func(ctx *context.Context){
+//		ctx.Output.Body([]byte(ctx.Input.Param(":tenant")))
+//	})
+func (p *ControllerRegister) Host(pattern string) *ControllerRegister {
+	pattern = strings.ToLower(pattern)
+	for _, h := range p.load().hostRouters {
+		if h.pattern == pattern {
+			return h.handlers
+		}
+	}
+	var handlers *ControllerRegister
+	p.update(func(t *routerTable) {
+		for _, h := range t.hostRouters {
+			if h.pattern == pattern {
+				handlers = h.handlers
+				return
+			}
+		}
+		h := newHostRouter(pattern)
+		t.hostRouters = append(t.hostRouters, h)
+		handlers = h.handlers
+	})
+	return handlers
+}
+
+// Host returns the ControllerRegister of BeeApp bound to the host pattern.
+// it's an alias method of ControllerRegister.Host.
+// usage:
+//  goweb.Host("admin.example.com").Add("/users", &admin.UserController{})
+//  goweb.Host(":tenant.example.com").Add("/users", &UserController{})
+func Host(pattern string) *ControllerRegister {
+	return BeeApp.Handlers.Host(pattern)
+}
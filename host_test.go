@@ -0,0 +1,149 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cooleo/goweb/context"
+)
+
+func hostRequest(method, host, path string) (*httptest.ResponseRecorder, *http.Request) {
+	rw, r := testRequest(method, path)
+	r.Host = host
+	return rw, r
+}
+
+func TestHostRouter(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Host("api.example.com").Get("/users", func(ctx *context.Context) {
+		ctx.WriteString("api")
+	})
+	handler.Host("admin.example.com").Get("/users", func(ctx *context.Context) {
+		ctx.WriteString("admin")
+	})
+	handler.Host(":tenant.example.com").Get("/users", func(ctx *context.Context) {
+		ctx.WriteString("tenant " + ctx.Input.Param(":tenant"))
+	})
+	handler.Host("*.example.org").Get("/users", func(ctx *context.Context) {
+		ctx.WriteString("org")
+	})
+	handler.Get("/users", func(ctx *context.Context) {
+		ctx.WriteString("default")
+	})
+
+	for host, want := range map[string]string{
+		"api.example.com":      "api",
+		"admin.example.com:80": "admin",
+		"Shop.example.com":     "tenant shop",
+		"a.b.example.org":      "org",
+		"example.net":          "default",
+	} {
+		rw, r := hostRequest("GET", host, "/users")
+		handler.ServeHTTP(rw, r)
+		if rw.Body.String() != want {
+			t.Errorf("%s: want %q, get %q", host, want, rw.Body.String())
+		}
+	}
+
+	rw, r := hostRequest("POST", "api.example.com", "/users")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Code set to [%v]; want [%v]", rw.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHostURLFor(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Host(":tenant.example.com").Add("/person/:last/:first", &TestController{}, "*:Param")
+	handler.Host("*.example.com").Add("/api/list", &TestController{}, "*:List")
+	if a := handler.URLFor("TestController.Param", ":tenant", "shop", ":last", "xie", ":first", "asta"); a != "http://shop.example.com/person/xie/asta" {
+		t.Errorf("TestController.Param must equal to http://shop.example.com/person/xie/asta, but get " + a)
+	}
+	if a := handler.URLFor("TestController.List"); a != "/api/list" {
+		t.Errorf("TestController.List must equal to /api/list, but get " + a)
+	}
+}
+
+func TestNamespaceHost(t *testing.T) {
+	ns := NewNamespace("/v1",
+		NSHost(":tenant.ns.example.com"),
+		NSGet("/info", func(ctx *context.Context) {
+			ctx.WriteString(ctx.Input.Param(":tenant"))
+		}),
+	)
+	AddNamespace(ns)
+
+	rw, r := hostRequest("GET", "shop.ns.example.com", "/v1/info")
+	BeeApp.Handlers.ServeHTTP(rw, r)
+	if rw.Body.String() != "shop" {
+		t.Errorf("TestNamespaceHost can't run, get the response is " + rw.Body.String())
+	}
+	rw, r = hostRequest("GET", "example.com", "/v1/info")
+	BeeApp.Handlers.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("Code set to [%v]; want [%v]", rw.Code, http.StatusNotFound)
+	}
+}
+
+func TestHostParamsOnMatch(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Host(":tenant.example.com").Get("/only", func(ctx *context.Context) {
+		ctx.WriteString("only")
+	})
+	handler.Get("/other", func(ctx *context.Context) {
+		ctx.WriteString("tenant=" + ctx.Input.Param(":tenant"))
+	})
+	rw, r := hostRequest("GET", "shop.example.com", "/other")
+	handler.ServeHTTP(rw, r)
+	if rw.Body.String() != "tenant=" {
+		t.Errorf("the host params should not leak into the host-less router, get %s", rw.Body.String())
+	}
+	if err := handler.Host(":tenant.example.com").InsertFilter("/*", BeforeRouter, func(ctx *context.Context) {}); err == nil {
+		t.Error("the filters of a host should be rejected")
+	}
+}
+
+func TestNestedNamespaceHost(t *testing.T) {
+	ns := NewNamespace("/v2",
+		NSNamespace("/shop",
+			NSHost(":tenant.nested.example.com"),
+			NSGet("/info", func(ctx *context.Context) {
+				ctx.WriteString(ctx.Input.Param(":tenant"))
+			}),
+		),
+	)
+	AddNamespace(ns)
+
+	rw, r := hostRequest("GET", "shop.nested.example.com", "/v2/shop/info")
+	BeeApp.Handlers.ServeHTTP(rw, r)
+	if rw.Body.String() != "shop" {
+		t.Errorf("the nested Namespace should be bound to the host, get %s", rw.Body.String())
+	}
+	rw, r = hostRequest("GET", "example.com", "/v2/shop/info")
+	BeeApp.Handlers.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("Code set to [%v]; want [%v]", rw.Code, http.StatusNotFound)
+	}
+
+	RemoveNamespace(ns)
+	rw, r = hostRequest("GET", "shop.nested.example.com", "/v2/shop/info")
+	BeeApp.Handlers.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("the removed Namespace should not be matched, get %d", rw.Code)
+	}
+}
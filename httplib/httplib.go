@@ -34,6 +34,7 @@ package httplib
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"encoding/xml"
@@ -253,6 +254,16 @@ func (b *gowebHTTPRequest) SetTransport(transport http.RoundTripper) *gowebHTTPR
 	return b
 }
 
+// WithContext binds the request to ctx,
+// the request is cancelled when ctx is done.
+// example:
+//
+//	httplib.Get("http://goweb.me/").WithContext(ctx.Request.Context()).String()
+func (b *gowebHTTPRequest) WithContext(ctx context.Context) *gowebHTTPRequest {
+	b.req = b.req.WithContext(ctx)
+	return b
+}
+
 // SetProxy set the http proxy
 // example:
 //
@@ -15,6 +15,7 @@
 package httplib
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -216,3 +217,12 @@ func TestHeader(t *testing.T) {
 	}
 	t.Log(str)
 }
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := Get("http://httpbin.org/get").WithContext(ctx)
+	if _, err := req.String(); err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Fatal("request should be cancelled with the context, get", err)
+	}
+}
@@ -17,6 +17,7 @@ package goweb
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	beecontext "github.com/cooleo/goweb/context"
 )
@@ -29,6 +30,8 @@ type LinkNamespace func(*Namespace)
 // Namespace is store all the info
 type Namespace struct {
 	prefix   string
+	host     string
+	timeout  *routeTimeout
 	handlers *ControllerRegister
 }
 
@@ -60,16 +63,67 @@ func (n *Namespace) Cond(cond namespaceCond) *Namespace {
 			exception("405", ctx)
 		}
 	}
-	if v, ok := n.handlers.filters[BeforeRouter]; ok {
-		mr := new(FilterRouter)
-		mr.tree = NewTree()
-		mr.pattern = "*"
-		mr.filterFunc = fn
-		mr.tree.AddRouter("*", true)
-		n.handlers.filters[BeforeRouter] = append([]*FilterRouter{mr}, v...)
-	} else {
-		n.handlers.InsertFilter("*", BeforeRouter, fn)
-	}
+	mr := new(FilterRouter)
+	mr.tree = NewTree()
+	mr.pattern = "*"
+	mr.filterFunc = fn
+	mr.tree.AddRouter("*", true)
+	n.handlers.update(func(t *routerTable) {
+		if v, ok := t.filters[BeforeRouter]; ok {
+			t.filters[BeforeRouter] = append([]*FilterRouter{mr}, v...)
+			t.enableFilter = true
+		} else {
+			mr.returnOnOutput = true
+			t.insertFilterRouter(BeforeRouter, mr)
+		}
+	})
+	return n
+}
+
+// Host binds the Namespace routers to the host pattern,
+// it works on the Namespace registered with AddNamespace or nested in another.
+// refer: ControllerRegister.Host
+// usage:
+// ns.Host(":tenant.example.com")
+func (n *Namespace) Host(pattern string) *Namespace {
+	n.host = pattern
+	return n
+}
+
+// Timeout sets the deadline of all the Namespace routers which have none,
+// it is applied when the Namespace is added.
+// refer: ControllerRegister.Timeout
+// usage:
+// ns.Timeout(2 * time.Second)
+func (n *Namespace) Timeout(d time.Duration, code ...int) *Namespace {
+	n.timeout = newRouteTimeout(d, code)
+	return n
+}
+
+// ErrorMode sets the error mode of the requests under the Namespace prefix,
+// including those not found, it overrides BConfig.ErrorMode.
+// usage:
+// ns.ErrorMode(goweb.ErrorModeAuto)
+func (n *Namespace) ErrorMode(mode string) *Namespace {
+	n.handlers.InsertFilter("*", BeforeStatic, func(ctx *beecontext.Context) {
+		ctx.Input.SetData(errorModeKey{}, mode)
+	})
+	return n
+}
+
+// Name sets the name of the last router added to the Namespace,
+// the url built by URLFor includes the Namespace prefix.
+// refer: ControllerRegister.Name
+// usage:
+// ns.Router("/user/:id", &UserController{}, "get:Show").Name("user.show")
+func (n *Namespace) Name(name string) *Namespace {
+	n.handlers.Name(name)
+	return n
+}
+
+// Params sets the argument sources of the controller method of the last router added to the Namespace.
+func (n *Namespace) Params(params ...string) *Namespace {
+	n.handlers.Params(params...)
 	return n
 }
 
@@ -103,6 +157,13 @@ func (n *Namespace) Router(rootpath string, c ControllerInterface, mappingMethod
 	return n
 }
 
+// RouterWithChain same as goweb.RouterWithChain
+// refer: https://godoc.org/github.com/cooleo/goweb#RouterWithChain
+func (n *Namespace) RouterWithChain(rootpath string, c ControllerInterface, chains []FilterChain, mappingMethods ...string) *Namespace {
+	n.handlers.AddWithChain(rootpath, c, chains, mappingMethods...)
+	return n
+}
+
 // AutoRouter same as goweb.AutoRouter
 // refer: https://godoc.org/github.com/cooleo/goweb#AutoRouter
 func (n *Namespace) AutoRouter(c ControllerInterface) *Namespace {
@@ -119,64 +180,68 @@ func (n *Namespace) AutoPrefix(prefix string, c ControllerInterface) *Namespace
 
 // Get same as goweb.Get
 // refer: https://godoc.org/github.com/cooleo/goweb#Get
-func (n *Namespace) Get(rootpath string, f FilterFunc) *Namespace {
-	n.handlers.Get(rootpath, f)
+func (n *Namespace) Get(rootpath string, f FilterFunc, chains ...FilterChain) *Namespace {
+	n.handlers.Get(rootpath, f, chains...)
 	return n
 }
 
 // Post same as goweb.Post
 // refer: https://godoc.org/github.com/cooleo/goweb#Post
-func (n *Namespace) Post(rootpath string, f FilterFunc) *Namespace {
-	n.handlers.Post(rootpath, f)
+func (n *Namespace) Post(rootpath string, f FilterFunc, chains ...FilterChain) *Namespace {
+	n.handlers.Post(rootpath, f, chains...)
 	return n
 }
 
 // Delete same as goweb.Delete
 // refer: https://godoc.org/github.com/cooleo/goweb#Delete
-func (n *Namespace) Delete(rootpath string, f FilterFunc) *Namespace {
-	n.handlers.Delete(rootpath, f)
+func (n *Namespace) Delete(rootpath string, f FilterFunc, chains ...FilterChain) *Namespace {
+	n.handlers.Delete(rootpath, f, chains...)
 	return n
 }
 
 // Put same as goweb.Put
 // refer: https://godoc.org/github.com/cooleo/goweb#Put
-func (n *Namespace) Put(rootpath string, f FilterFunc) *Namespace {
-	n.handlers.Put(rootpath, f)
+func (n *Namespace) Put(rootpath string, f FilterFunc, chains ...FilterChain) *Namespace {
+	n.handlers.Put(rootpath, f, chains...)
 	return n
 }
 
 // Head same as goweb.Head
 // refer: https://godoc.org/github.com/cooleo/goweb#Head
-func (n *Namespace) Head(rootpath string, f FilterFunc) *Namespace {
-	n.handlers.Head(rootpath, f)
+func (n *Namespace) Head(rootpath string, f FilterFunc, chains ...FilterChain) *Namespace {
+	n.handlers.Head(rootpath, f, chains...)
 	return n
 }
 
 // Options same as goweb.Options
 // refer: https://godoc.org/github.com/cooleo/goweb#Options
-func (n *Namespace) Options(rootpath string, f FilterFunc) *Namespace {
-	n.handlers.Options(rootpath, f)
+func (n *Namespace) Options(rootpath string, f FilterFunc, chains ...FilterChain) *Namespace {
+	n.handlers.Options(rootpath, f, chains...)
 	return n
 }
 
 // Patch same as goweb.Patch
 // refer: https://godoc.org/github.com/cooleo/goweb#Patch
-func (n *Namespace) Patch(rootpath string, f FilterFunc) *Namespace {
-	n.handlers.Patch(rootpath, f)
+func (n *Namespace) Patch(rootpath string, f FilterFunc, chains ...FilterChain) *Namespace {
+	n.handlers.Patch(rootpath, f, chains...)
 	return n
 }
 
 // Any same as goweb.Any
 // refer: https://godoc.org/github.com/cooleo/goweb#Any
-func (n *Namespace) Any(rootpath string, f FilterFunc) *Namespace {
-	n.handlers.Any(rootpath, f)
+func (n *Namespace) Any(rootpath string, f FilterFunc, chains ...FilterChain) *Namespace {
+	n.handlers.Any(rootpath, f, chains...)
 	return n
 }
 
 // Handler same as goweb.Handler
 // refer: https://godoc.org/github.com/cooleo/goweb#Handler
-func (n *Namespace) Handler(rootpath string, h http.Handler) *Namespace {
-	n.handlers.Handler(rootpath, h)
+func (n *Namespace) Handler(rootpath string, h http.Handler, chains ...FilterChain) *Namespace {
+	options := make([]interface{}, 0, len(chains))
+	for _, c := range chains {
+		options = append(options, c)
+	}
+	n.handlers.Handler(rootpath, h, options...)
 	return n
 }
 
@@ -206,27 +271,17 @@ func (n *Namespace) Include(cList ...ControllerInterface) *Namespace {
 //)
 func (n *Namespace) Namespace(ns ...*Namespace) *Namespace {
 	for _, ni := range ns {
-		for k, v := range ni.handlers.routers {
-			if t, ok := n.handlers.routers[k]; ok {
-				addPrefix(v, ni.prefix)
-				n.handlers.routers[k].AddTree(ni.prefix, v)
-			} else {
-				t = NewTree()
-				t.AddTree(ni.prefix, v)
-				addPrefix(t, ni.prefix)
-				n.handlers.routers[k] = t
-			}
-		}
-		if ni.handlers.enableFilter {
-			for pos, filterList := range ni.handlers.filters {
-				for _, mr := range filterList {
-					t := NewTree()
-					t.AddTree(ni.prefix, mr.tree)
-					mr.tree = t
-					n.handlers.insertFilterRouter(pos, mr)
-				}
-			}
+		handlers := n.handlers
+		if ni.host != "" {
+			handlers = n.handlers.Host(ni.host)
 		}
+		handlers.update(func(t *routerTable) {
+			ni.addRouters(t)
+		})
+		n.handlers.update(func(t *routerTable) {
+			ni.addFilters(t)
+		})
+		ni.addHostRouters(n.handlers)
 	}
 	return n
 }
@@ -235,27 +290,91 @@ func (n *Namespace) Namespace(ns ...*Namespace) *Namespace {
 // support multi Namespace
 func AddNamespace(nl ...*Namespace) {
 	for _, n := range nl {
-		for k, v := range n.handlers.routers {
-			if t, ok := BeeApp.Handlers.routers[k]; ok {
-				addPrefix(v, n.prefix)
-				BeeApp.Handlers.routers[k].AddTree(n.prefix, v)
-			} else {
-				t = NewTree()
-				t.AddTree(n.prefix, v)
-				addPrefix(t, n.prefix)
-				BeeApp.Handlers.routers[k] = t
-			}
+		handlers := BeeApp.Handlers
+		if n.host != "" {
+			handlers = BeeApp.Handlers.Host(n.host)
+		}
+		handlers.update(func(t *routerTable) {
+			n.addRouters(t)
+		})
+		BeeApp.Handlers.update(func(t *routerTable) {
+			n.addFilters(t)
+		})
+		n.addHostRouters(BeeApp.Handlers)
+	}
+}
+
+// addRouters adds the routers and names of the Namespace to t with its prefix.
+// the trees of the Namespace are cloned, so that it can be added again after removed.
+func (n *Namespace) addRouters(t *routerTable) {
+	n.addTable(t, n.handlers.load())
+}
+
+// addHostRouters adds the routers of the nested Namespaces bound to hosts
+// to the host routers of handlers with the Namespace prefix.
+func (n *Namespace) addHostRouters(handlers *ControllerRegister) {
+	for _, h := range n.handlers.load().hostRouters {
+		ht := h.handlers.load()
+		handlers.Host(h.pattern).update(func(t *routerTable) {
+			n.addTable(t, ht)
+		})
+	}
+}
+
+// addTable adds the copies of the routers and names of nt to t with the Namespace prefix,
+// the routers of nt may be used by the requests in flight if the Namespace is added again.
+func (n *Namespace) addTable(t, nt *routerTable) {
+	copies := make(map[*controllerInfo]*controllerInfo)
+	for k, v := range nt.routers {
+		v = v.clone()
+		copyRouters(v, copies)
+		if n.timeout != nil {
+			setTimeout(v, n.timeout)
+		}
+		addPrefix(v, n.prefix)
+		t.addTree(k, n.prefix, v)
+	}
+	for name, r := range nt.names {
+		if c, ok := copies[r]; ok {
+			r = c
 		}
-		if n.handlers.enableFilter {
-			for pos, filterList := range n.handlers.filters {
-				for _, mr := range filterList {
-					t := NewTree()
-					t.AddTree(n.prefix, mr.tree)
-					mr.tree = t
-					BeeApp.Handlers.insertFilterRouter(pos, mr)
+		t.addName(name, r)
+	}
+}
+
+// copyRouters replaces the routers of the tree by their copies kept in copies.
+func copyRouters(t *Tree, copies map[*controllerInfo]*controllerInfo) {
+	walkTree(t, func(t *Tree) {
+		for _, l := range t.leaves {
+			if r, ok := l.runObject.(*controllerInfo); ok {
+				c, ok := copies[r]
+				if !ok {
+					rc := *r
+					rc.origin = r
+					c = &rc
+					copies[r] = c
 				}
+				l.runObject = c
 			}
 		}
+	})
+}
+
+// addFilters adds the copies of the Namespace filters to t with its prefix.
+func (n *Namespace) addFilters(t *routerTable) {
+	nt := n.handlers.load()
+	if !nt.enableFilter {
+		return
+	}
+	for pos, filterList := range nt.filters {
+		for _, mr := range filterList {
+			tr := NewTree()
+			tr.AddTree(n.prefix, mr.tree.clone())
+			m := *mr
+			m.tree = tr
+			m.origin = mr
+			t.insertFilterRouter(pos, &m)
+		}
 	}
 }
 
@@ -270,6 +389,7 @@ func addPrefix(t *Tree, prefix string) {
 		if c, ok := l.runObject.(*controllerInfo); ok {
 			if !strings.HasPrefix(c.pattern, prefix) {
 				c.pattern = prefix + c.pattern
+				c.pathParams = patternParams(c.pattern)
 			}
 		}
 	}
@@ -283,6 +403,41 @@ func NSCond(cond namespaceCond) LinkNamespace {
 	}
 }
 
+// NSHost bind the Namespace routers to the host pattern
+func NSHost(pattern string) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.Host(pattern)
+	}
+}
+
+// NSTimeout sets the deadline of the Namespace routers
+func NSTimeout(d time.Duration, code ...int) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.Timeout(d, code...)
+	}
+}
+
+// NSErrorMode sets the error mode of the Namespace.
+func NSErrorMode(mode string) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.ErrorMode(mode)
+	}
+}
+
+// NSName names the router added by the previous LinkNamespace
+func NSName(name string) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.Name(name)
+	}
+}
+
+// NSParams sets the argument sources of the router added by the previous LinkNamespace
+func NSParams(params ...string) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.Params(params...)
+	}
+}
+
 // NSBefore Namespace BeforeRouter filter
 func NSBefore(filiterList ...FilterFunc) LinkNamespace {
 	return func(ns *Namespace) {
@@ -311,59 +466,66 @@ func NSRouter(rootpath string, c ControllerInterface, mappingMethods ...string)
 	}
 }
 
+// NSRouterWithChain call Namespace RouterWithChain
+func NSRouterWithChain(rootpath string, c ControllerInterface, chains []FilterChain, mappingMethods ...string) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.RouterWithChain(rootpath, c, chains, mappingMethods...)
+	}
+}
+
 // NSGet call Namespace Get
-func NSGet(rootpath string, f FilterFunc) LinkNamespace {
+func NSGet(rootpath string, f FilterFunc, chains ...FilterChain) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Get(rootpath, f)
+		ns.Get(rootpath, f, chains...)
 	}
 }
 
 // NSPost call Namespace Post
-func NSPost(rootpath string, f FilterFunc) LinkNamespace {
+func NSPost(rootpath string, f FilterFunc, chains ...FilterChain) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Post(rootpath, f)
+		ns.Post(rootpath, f, chains...)
 	}
 }
 
 // NSHead call Namespace Head
-func NSHead(rootpath string, f FilterFunc) LinkNamespace {
+func NSHead(rootpath string, f FilterFunc, chains ...FilterChain) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Head(rootpath, f)
+		ns.Head(rootpath, f, chains...)
 	}
 }
 
 // NSPut call Namespace Put
-func NSPut(rootpath string, f FilterFunc) LinkNamespace {
+func NSPut(rootpath string, f FilterFunc, chains ...FilterChain) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Put(rootpath, f)
+		ns.Put(rootpath, f, chains...)
 	}
 }
 
 // NSDelete call Namespace Delete
-func NSDelete(rootpath string, f FilterFunc) LinkNamespace {
+func NSDelete(rootpath string, f FilterFunc, chains ...FilterChain) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Delete(rootpath, f)
+		ns.Delete(rootpath, f, chains...)
 	}
 }
 
 // NSAny call Namespace Any
-func NSAny(rootpath string, f FilterFunc) LinkNamespace {
+func NSAny(rootpath string, f FilterFunc, chains ...FilterChain) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Any(rootpath, f)
+		ns.Any(rootpath, f, chains...)
 	}
 }
 
 // NSOptions call Namespace Options
-func NSOptions(rootpath string, f FilterFunc) LinkNamespace {
+func NSOptions(rootpath string, f FilterFunc, chains ...FilterChain) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Options(rootpath, f)
+		ns.Options(rootpath, f, chains...)
 	}
 }
 
 // NSPatch call Namespace Patch
-func NSPatch(rootpath string, f FilterFunc) LinkNamespace {
+func NSPatch(rootpath string, f FilterFunc, chains ...FilterChain) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Patch(rootpath, f)
+		ns.Patch(rootpath, f, chains...)
 	}
 }
 
@@ -390,8 +552,8 @@ func NSNamespace(prefix string, params ...LinkNamespace) LinkNamespace {
 }
 
 // NSHandler add handler
-func NSHandler(rootpath string, h http.Handler) LinkNamespace {
+func NSHandler(rootpath string, h http.Handler, chains ...FilterChain) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Handler(rootpath, h)
+		ns.Handler(rootpath, h, chains...)
 	}
 }
@@ -18,7 +18,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cooleo/goweb/context"
 )
@@ -169,3 +171,125 @@ func TestNamespaceInside(t *testing.T) {
 		t.Errorf("TestNamespaceInside can't run, get the response is " + w.Body.String())
 	}
 }
+
+func TestNamespaceChain(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/v3/chain", nil)
+	w := httptest.NewRecorder()
+
+	ns := NewNamespace("/v3",
+		NSGet("/chain", func(ctx *context.Context) {
+			ctx.WriteString("v3_chain")
+		}, chainTag("ns")),
+	)
+	AddNamespace(ns)
+	BeeApp.Handlers.ServeHTTP(w, r)
+	if w.Body.String() != "ns>v3_chain" {
+		t.Errorf("TestNamespaceChain can't run, get the response is " + w.Body.String())
+	}
+}
+
+func TestNamespaceName(t *testing.T) {
+	ns := NewNamespace("/v4",
+		NSNamespace("/shop",
+			NSGet("/:id", func(ctx *context.Context) {}),
+			NSName("v4.shop.show"),
+		),
+	)
+	AddNamespace(ns)
+	if a := URLFor("v4.shop.show", ":id", 12); a != "/v4/shop/12" {
+		t.Errorf("v4.shop.show must equal to /v4/shop/12, but get " + a)
+	}
+}
+
+func TestNamespaceTimeout(t *testing.T) {
+	slow := func(ctx *context.Context) {
+		<-ctx.Context().Done()
+	}
+	ns := NewNamespace("/v5",
+		NSTimeout(20*time.Millisecond),
+		NSGet("/slow", slow),
+		NSNamespace("/inner",
+			NSTimeout(10*time.Millisecond, http.StatusGatewayTimeout),
+			NSGet("/slow", slow),
+		),
+	)
+	AddNamespace(ns)
+
+	for url, code := range map[string]int{"/v5/slow": http.StatusServiceUnavailable, "/v5/inner/slow": http.StatusGatewayTimeout} {
+		r, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		BeeApp.Handlers.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Errorf("%s should time out with %d, get %d", url, code, w.Code)
+		}
+	}
+}
+
+func TestRemoveNamespace(t *testing.T) {
+	ns := NewNamespace("/v6",
+		NSBefore(func(ctx *context.Context) {
+			ctx.Output.Header("X-Plugin", "v6")
+		}),
+		NSGet("/plugin", func(ctx *context.Context) {
+			ctx.Output.Body([]byte("plugin"))
+		}),
+		NSName("plugin"),
+	)
+	for i := 0; i < 2; i++ {
+		AddNamespace(ns)
+		r, _ := http.NewRequest("GET", "/v6/plugin", nil)
+		w := httptest.NewRecorder()
+		BeeApp.Handlers.ServeHTTP(w, r)
+		if w.Body.String() != "plugin" || w.Header().Get("X-Plugin") != "v6" {
+			t.Errorf("/v6/plugin should be mounted, get %d %s", w.Code, w.Body.String())
+		}
+		if URLFor("plugin") != "/v6/plugin" {
+			t.Errorf("plugin should be named, get %s", URLFor("plugin"))
+		}
+
+		RemoveNamespace(ns)
+		r, _ = http.NewRequest("GET", "/v6/plugin", nil)
+		w = httptest.NewRecorder()
+		BeeApp.Handlers.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound || w.Header().Get("X-Plugin") != "" {
+			t.Errorf("/v6/plugin should be unmounted, get %d", w.Code)
+		}
+		if _, ok := BeeApp.Handlers.load().names["plugin"]; ok {
+			t.Error("the name of the unmounted router should be released")
+		}
+	}
+	if r := ns.handlers.load().names["plugin"]; r.pattern != "/plugin" {
+		t.Errorf("the router of the Namespace should not be prefixed, get %s", r.pattern)
+	}
+}
+
+func TestNamespaceErrorMode(t *testing.T) {
+	ns := NewNamespace("/v7",
+		NSErrorMode(ErrorModeProblem),
+		NSGet("/user", func(ctx *context.Context) {
+			ctx.Output.Body([]byte("user"))
+		}),
+	)
+	AddNamespace(ns)
+	defer RemoveNamespace(ns)
+
+	r, _ := http.NewRequest("POST", "/v7/user", nil)
+	w := httptest.NewRecorder()
+	BeeApp.Handlers.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Content-Type") != "application/problem+json" ||
+		!strings.Contains(w.Body.String(), `"instance":"/v7/user"`) {
+		t.Errorf("the namespace should render 405 as a problem, get %d %s", w.Code, w.Body.String())
+	}
+	r, _ = http.NewRequest("GET", "/v7/none", nil)
+	w = httptest.NewRecorder()
+	BeeApp.Handlers.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("the namespace should render 404 as a problem, get %d %s", w.Code, w.Body.String())
+	}
+	r, _ = http.NewRequest("GET", "/v8/none", nil)
+	w = httptest.NewRecorder()
+	BeeApp.Handlers.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") == "application/problem+json" {
+		t.Errorf("the paths out of the namespace shouldn't render problems, get %d %s", w.Code, w.Body.String())
+	}
+}
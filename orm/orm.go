@@ -52,6 +52,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -91,6 +92,7 @@ type orm struct {
 	alias *alias
 	db    dbQuerier
 	isTx  bool
+	ctx   context.Context
 }
 
 var _ Ormer = new(orm)
@@ -425,9 +427,9 @@ func (o *orm) Using(name string) error {
 	if al, ok := dataBaseCache.get(name); ok {
 		o.alias = al
 		if Debug {
-			o.db = newDbQueryLog(al, al.DB)
+			o.db = newDbQueryLog(al, withContext(o.ctx, al.DB))
 		} else {
-			o.db = al.DB
+			o.db = withContext(o.ctx, al.DB)
 		}
 	} else {
 		return fmt.Errorf("<Ormer.Using> unknown db alias name `%s`", name)
@@ -447,9 +449,9 @@ func (o *orm) Begin() error {
 	}
 	o.isTx = true
 	if Debug {
-		o.db.(*dbQueryLog).SetDB(tx)
+		o.db.(*dbQueryLog).SetDB(withContext(o.ctx, tx))
 	} else {
-		o.db = tx
+		o.db = withContext(o.ctx, tx)
 	}
 	return nil
 }
@@ -0,0 +1,120 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// database querier supporting context, *sql.DB and *sql.Tx
+type dbQuerierContext interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// transaction beginner supporting context
+type txerContext interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// dbQueryContext runs the queries with ctx,
+// so that they are cancelled when ctx is done.
+type dbQueryContext struct {
+	ctx context.Context
+	db  dbQuerier
+}
+
+var _ dbQuerier = new(dbQueryContext)
+var _ txer = new(dbQueryContext)
+var _ txEnder = new(dbQueryContext)
+
+func (d *dbQueryContext) Prepare(query string) (*sql.Stmt, error) {
+	if db, ok := d.db.(dbQuerierContext); ok {
+		return db.PrepareContext(d.ctx, query)
+	}
+	return d.db.Prepare(query)
+}
+
+func (d *dbQueryContext) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if db, ok := d.db.(dbQuerierContext); ok {
+		return db.ExecContext(d.ctx, query, args...)
+	}
+	return d.db.Exec(query, args...)
+}
+
+func (d *dbQueryContext) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if db, ok := d.db.(dbQuerierContext); ok {
+		return db.QueryContext(d.ctx, query, args...)
+	}
+	return d.db.Query(query, args...)
+}
+
+func (d *dbQueryContext) QueryRow(query string, args ...interface{}) *sql.Row {
+	if db, ok := d.db.(dbQuerierContext); ok {
+		return db.QueryRowContext(d.ctx, query, args...)
+	}
+	return d.db.QueryRow(query, args...)
+}
+
+func (d *dbQueryContext) Begin() (*sql.Tx, error) {
+	if db, ok := d.db.(txerContext); ok {
+		return db.BeginTx(d.ctx, nil)
+	}
+	return d.db.(txer).Begin()
+}
+
+func (d *dbQueryContext) Commit() error {
+	return d.db.(txEnder).Commit()
+}
+
+func (d *dbQueryContext) Rollback() error {
+	return d.db.(txEnder).Rollback()
+}
+
+// withContext binds db to ctx, a nil ctx returns the db unbound.
+func withContext(ctx context.Context, db dbQuerier) dbQuerier {
+	if d, ok := db.(*dbQueryContext); ok {
+		db = d.db
+	}
+	if ctx == nil {
+		return db
+	}
+	return &dbQueryContext{ctx: ctx, db: db}
+}
+
+// WithContext returns a copy of o whose queries, including the transaction,
+// are bound to ctx, so that they are cancelled when ctx is done. o isn't changed.
+// the Ormer not created by NewOrm is returned as it is.
+// for example:
+//	o := orm.WithContext(ctx.Request.Context(), orm.NewOrm())
+func WithContext(ctx context.Context, o Ormer) Ormer {
+	om, ok := o.(*orm)
+	if !ok {
+		return o
+	}
+	c := *om
+	c.ctx = ctx
+	if d, ok := c.db.(*dbQueryLog); ok {
+		dc := *d
+		dc.SetDB(withContext(ctx, d.db))
+		c.db = &dc
+	} else {
+		c.db = withContext(ctx, c.db)
+	}
+	return &c
+}
@@ -0,0 +1,123 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// QueryIterator reads the rows of a QuerySeter batch by batch,
+// so that a large result needn't be loaded in memory at once.
+// the batches are paged by the ordering column, which must be unique, e.g. the primary key,
+// a batch reads the rows after the last row of the previous one rather than an offset,
+// so the rows inserted or deleted while iterating don't shift the following batches.
+// the QuerySeter is ordered by the primary key if it isn't ordered.
+// usage:
+//	it := orm.NewQueryIterator(o.QueryTable("user").OrderBy("Id"), &[]*User{}, 500)
+//	c.ServeJSONArray(it.Next)
+type QueryIterator struct {
+	qs    QuerySeter
+	ind   reflect.Value
+	cols  []string
+	batch int
+	order *fieldInfo
+	desc  bool
+	last  interface{}
+	index int
+	done  bool
+}
+
+// NewQueryIterator returns a QueryIterator reading batch rows at a time into container,
+// a pointer to a slice of the model, e.g. &[]*User{} or &[]User{}.
+// qs must be ordered by at most one column of the model.
+// cols means the columns when querying, the ordering column is added to them.
+func NewQueryIterator(qs QuerySeter, container interface{}, batch int, cols ...string) *QueryIterator {
+	val := reflect.ValueOf(container)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		panic(fmt.Errorf("<orm.NewQueryIterator> container must be a pointer to slice, got %T", container))
+	}
+	if batch <= 0 {
+		batch = DefaultRowsLimit
+	}
+	it := &QueryIterator{
+		ind:   val,
+		batch: batch,
+	}
+	q := qs.(*querySet)
+	switch len(q.orders) {
+	case 0:
+		it.order = q.mi.fields.pk
+		qs = qs.OrderBy(it.order.name)
+	case 1:
+		name := q.orders[0]
+		it.desc = strings.HasPrefix(name, "-")
+		fi, ok := q.mi.fields.GetByAny(strings.TrimPrefix(name, "-"))
+		if !ok || fi.rel || fi.reverse {
+			panic(fmt.Errorf("<orm.NewQueryIterator> `%s` is not a column of model `%s`", name, q.mi.fullName))
+		}
+		it.order = fi
+	default:
+		panic(fmt.Errorf("<orm.NewQueryIterator> QuerySeter must be ordered by one column, got %v", q.orders))
+	}
+	it.qs = qs
+	if len(cols) > 0 {
+		it.cols = cols
+		found := false
+		for _, col := range cols {
+			if fi, ok := q.mi.fields.GetByAny(col); ok && fi == it.order {
+				found = true
+				break
+			}
+		}
+		if !found {
+			it.cols = append(cols[:len(cols):len(cols)], it.order.name)
+		}
+	}
+	return it
+}
+
+// Next returns the next row, io.EOF is returned after the last row.
+func (it *QueryIterator) Next() (interface{}, error) {
+	if it.index >= it.ind.Elem().Len() {
+		if it.done {
+			return nil, io.EOF
+		}
+		qs := it.qs
+		if it.last != nil {
+			op := "__gt"
+			if it.desc {
+				op = "__lt"
+			}
+			qs = qs.Filter(it.order.name+op, it.last)
+		}
+		num, err := qs.Limit(it.batch).All(it.ind.Interface(), it.cols...)
+		if err != nil {
+			return nil, err
+		}
+		it.index = 0
+		it.done = num < int64(it.batch)
+		if num == 0 {
+			return nil, io.EOF
+		}
+		row := reflect.Indirect(it.ind.Elem().Index(int(num) - 1))
+		it.last = row.FieldByIndex(it.order.fieldIndex).Interface()
+	}
+	v := it.ind.Elem().Index(it.index).Interface()
+	it.index++
+	return v, nil
+}
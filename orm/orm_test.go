@@ -16,8 +16,10 @@ package orm
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -903,6 +905,53 @@ func TestOffset(t *testing.T) {
 	throwFail(t, AssertIs(num, 2))
 }
 
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	o := WithContext(ctx, dORM)
+	throwFailNow(t, AssertIs(o != dORM, true))
+	cancel()
+
+	var user User
+	err := o.QueryTable("user").Filter("user_name", "slene").One(&user)
+	throwFail(t, AssertIs(err, context.Canceled))
+
+	err = dORM.QueryTable("user").Filter("user_name", "slene").One(&user)
+	throwFail(t, err)
+}
+
+func TestQueryIterator(t *testing.T) {
+	it := NewQueryIterator(dORM.QueryTable("post").OrderBy("Id"), &[]*Post{}, 3)
+	var ids []int
+	for {
+		v, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		throwFailNow(t, err)
+		ids = append(ids, v.(*Post).ID)
+	}
+	throwFail(t, AssertIs(len(ids), 4))
+	throwFail(t, AssertIs(ids[0] < ids[3], true))
+
+	it = NewQueryIterator(dORM.QueryTable("post").OrderBy("-Id"), &[]Post{}, 3, "Title")
+	var desc []int
+	for {
+		v, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		throwFailNow(t, err)
+		desc = append(desc, v.(Post).ID)
+	}
+	throwFail(t, AssertIs(len(desc), 4))
+	throwFail(t, AssertIs(desc[0], ids[3]))
+	throwFail(t, AssertIs(desc[3], ids[0]))
+
+	it = NewQueryIterator(dORM.QueryTable("user").Filter("user_name", "nothing"), &[]User{}, 3)
+	_, err := it.Next()
+	throwFail(t, AssertIs(err, io.EOF))
+}
+
 func TestOrderBy(t *testing.T) {
 	qs := dORM.QueryTable("user")
 	num, err := qs.OrderBy("-status").Filter("user_name", "nobody").Count()
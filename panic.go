@@ -0,0 +1,184 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cooleo/goweb/context"
+)
+
+// PanicReport is the report of a panic recovered while serving a request.
+type PanicReport struct {
+	Time       time.Time   `json:"time"`
+	App        string      `json:"app"`
+	Error      string      `json:"error"`
+	Stack      string      `json:"stack"`
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	RemoteAddr string      `json:"remote_addr"`
+	Headers    http.Header `json:"headers"`
+	Pattern    string      `json:"pattern,omitempty"`
+	SessionID  string      `json:"session_id,omitempty"`
+	// the value passed to panic
+	Value interface{} `json:"-"`
+}
+
+// PanicReporter reports the panics recovered while serving the requests.
+type PanicReporter interface {
+	Report(r *PanicReport)
+}
+
+// PanicReporterFunc is an adapter to use a function as PanicReporter.
+type PanicReporterFunc func(r *PanicReport)
+
+// Report calls f(r).
+func (f PanicReporterFunc) Report(r *PanicReport) {
+	f(r)
+}
+
+// RedactedHeaders are the request headers whose values are replaced by [REDACTED] in the panic reports.
+var RedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Csrftoken", "X-Xsrftoken", "X-Api-Key"}
+
+var (
+	panicReportersLock sync.RWMutex
+	panicReporters     []PanicReporter
+)
+
+// AddPanicReporter adds the PanicReporter of the recovered panics,
+// the panics are logged as JSON lines by JSONPanicReporter if none is added.
+// usage:
+//	goweb.AddPanicReporter(goweb.JSONPanicReporter(nil))
+//	goweb.AddPanicReporter(goweb.FilePanicReporter("/var/crash/myapp"))
+func AddPanicReporter(r PanicReporter) *App {
+	panicReportersLock.Lock()
+	defer panicReportersLock.Unlock()
+	panicReporters = append(panicReporters, r)
+	return BeeApp
+}
+
+// routerPatternKey is the key of the matched router pattern in the input data.
+type routerPatternKey struct{}
+
+// newPanicReport returns the report of the panic err with the stack of the goroutine.
+func newPanicReport(ctx *context.Context, err interface{}, stack []byte) *PanicReport {
+	r := &PanicReport{
+		Time:  time.Now(),
+		App:   BConfig.AppName,
+		Error: fmt.Sprint(err),
+		Stack: string(stack),
+		Value: err,
+	}
+	if ctx.Request != nil {
+		r.Method = ctx.Request.Method
+		r.URL = ctx.Request.URL.String()
+		r.RemoteAddr = ctx.Request.RemoteAddr
+		r.Headers = redactHeaders(ctx.Request.Header)
+	}
+	if pattern, ok := ctx.Input.GetData(routerPatternKey{}).(string); ok {
+		r.Pattern = pattern
+	}
+	if ctx.Input.CruSession != nil {
+		r.SessionID = ctx.Input.CruSession.SessionID()
+	}
+	return r
+}
+
+func redactHeaders(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = v
+	}
+	for _, k := range RedactedHeaders {
+		k = http.CanonicalHeaderKey(k)
+		if _, ok := c[k]; ok {
+			c[k] = []string{"[REDACTED]"}
+		}
+	}
+	return c
+}
+
+// reportPanic sends the report to the PanicReporters,
+// a reporter panicking doesn't stop the others.
+func reportPanic(r *PanicReport) {
+	panicReportersLock.RLock()
+	reporters := panicReporters
+	panicReportersLock.RUnlock()
+	if len(reporters) == 0 {
+		reporters = []PanicReporter{defaultPanicReporter}
+	}
+	for _, reporter := range reporters {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					Critical("the panic reporter crashed with error", err)
+				}
+			}()
+			reporter.Report(r)
+		}()
+	}
+}
+
+var defaultPanicReporter = JSONPanicReporter(nil)
+
+// JSONPanicReporter writes each report as a JSON line to w,
+// or logs it by Critical if w is nil.
+func JSONPanicReporter(w io.Writer) PanicReporter {
+	var mu sync.Mutex
+	return PanicReporterFunc(func(r *PanicReport) {
+		content, err := json.Marshal(r)
+		if err != nil {
+			Critical("the panic report can't be encoded", err)
+			return
+		}
+		if w == nil {
+			Critical(string(content))
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write(append(content, '\n'))
+	})
+}
+
+// FilePanicReporter writes each report as a crash dump file in dir,
+// named by the time of the panic, eg. crash-20160102-150405.000-1.json.
+func FilePanicReporter(dir string) PanicReporter {
+	var seq uint64
+	return PanicReporterFunc(func(r *PanicReport) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			Critical("the crash dump directory can't be created", err)
+			return
+		}
+		content, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			Critical("the panic report can't be encoded", err)
+			return
+		}
+		name := fmt.Sprintf("crash-%s-%d.json", r.Time.Format("20060102-150405.000"), atomic.AddUint64(&seq, 1))
+		if err = ioutil.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+			Critical("the crash dump can't be written", err)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cooleo/goweb/context"
+)
+
+func TestPanicReporter(t *testing.T) {
+	var reports []*PanicReport
+	dir, err := ioutil.TempDir("", "goweb-crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	reporters := panicReporters
+	panicReporters = nil
+	AddPanicReporter(PanicReporterFunc(func(r *PanicReport) {
+		reports = append(reports, r)
+	}))
+	AddPanicReporter(PanicReporterFunc(func(r *PanicReport) {
+		panic("the reporter crashed")
+	}))
+	AddPanicReporter(FilePanicReporter(dir))
+	defer func() { panicReporters = reporters }()
+
+	handler := NewControllerRegister()
+	handler.Get("/crash/:id", func(ctx *context.Context) {
+		panic("boom")
+	})
+	r, _ := http.NewRequest("GET", "/crash/3?q=1", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Request-Id", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(reports) != 1 {
+		t.Fatalf("the panic should be reported once, get %d", len(reports))
+	}
+	report := reports[0]
+	if report.Error != "boom" || report.Method != "GET" || report.URL != "/crash/3?q=1" || report.Pattern != "/crash/:id" {
+		t.Errorf("unexpected panic report %+v", report)
+	}
+	if report.Headers.Get("Authorization") != "[REDACTED]" || report.Headers.Get("X-Request-Id") != "abc" ||
+		r.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("only the sensitive headers should be redacted in the report, get %v", report.Headers)
+	}
+	if !strings.Contains(report.Stack, "TestPanicReporter") {
+		t.Errorf("the report should have the goroutine stack, get %s", report.Stack)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("the crash should be dumped after the crashed reporter, get %d files", len(files))
+	}
+	content, _ := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	if !strings.Contains(string(content), `"pattern": "/crash/:id"`) {
+		t.Errorf("unexpected crash dump %s", content)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	beecontext "github.com/cooleo/goweb/context"
+)
+
+// the sources of the controller method arguments
+const (
+	paramInPath   = "path"
+	paramInQuery  = "query"
+	paramInHeader = "header"
+	paramInBody   = "body"
+)
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	timeType  = reflect.TypeOf(time.Time{})
+)
+
+// methodParam is the source of a controller method argument.
+type methodParam struct {
+	name string
+	in   string
+}
+
+func newMethodParam(name, in string) *methodParam {
+	switch in {
+	case paramInPath, paramInQuery, paramInHeader, paramInBody:
+	default:
+		panic("param " + name + " has an invalid source " + in + ", it should be path, query, header or body")
+	}
+	return &methodParam{name: name, in: in}
+}
+
+// parseMethodParams parses the params of ControllerComments,
+// each of them maps the argument name to its source.
+func parseMethodParams(params []map[string]string) []*methodParam {
+	mps := make([]*methodParam, 0, len(params))
+	for _, p := range params {
+		for name, in := range p {
+			mps = append(mps, newMethodParam(name, in))
+		}
+	}
+	return mps
+}
+
+// Params sets the sources of the controller method arguments of the last added router,
+// by the order of the arguments, each param is "name:source",
+// source is one of path, query, header and body.
+// the arguments without param are bound by their types,
+// a struct, struct pointer or map is decoded from body except time.Time,
+// the others, including the named scalars like "type UserID int64", take the path params in order.
+// usage:
+//	func (c *UserController) Show(id int64, q *SearchQuery) (*User, error)
+//	Add("/user/:id",&UserController{},"get:Show")
+//	Params("id:path", "q:query")
+func (p *ControllerRegister) Params(params ...string) *ControllerRegister {
+	mps := make([]*methodParam, 0, len(params))
+	for _, param := range params {
+		i := strings.LastIndex(param, ":")
+		if i <= 0 {
+			panic("param " + param + " is invalid, it should be name:source")
+		}
+		mps = append(mps, newMethodParam(param[:i], param[i+1:]))
+	}
+	p.updateLast("set params", func(t *routerTable, r *controllerInfo) {
+		r.params = mps
+	})
+	return p
+}
+
+// patternParams returns the names of the path params of pattern in order.
+func patternParams(pattern string) []string {
+	if !BConfig.RouterCaseSensitive {
+		pattern = strings.ToLower(pattern)
+	}
+	var names []string
+	for _, seg := range splitPath(pattern) {
+		_, params, _ := splitSegment(seg)
+		for _, param := range params {
+			if strings.HasPrefix(param, ":") {
+				names = append(names, param)
+			}
+		}
+	}
+	return names
+}
+
+// actionArgs binds the arguments of the controller method from the request,
+// the last argument of a variadic method is bound as a slice, which is passed by CallSlice.
+func actionArgs(context *beecontext.Context, method reflect.Type, params []*methodParam, pathParams []string) ([]reflect.Value, error) {
+	in := make([]reflect.Value, method.NumIn())
+	for i := range in {
+		typ := method.In(i)
+		dest := reflect.New(typ)
+		var param *methodParam
+		if i < len(params) {
+			param = params[i]
+		} else if isBodyType(typ) {
+			param = &methodParam{in: paramInBody}
+		} else if len(pathParams) > 0 {
+			param = &methodParam{name: pathParams[0], in: paramInPath}
+			pathParams = pathParams[1:]
+		}
+		if param != nil {
+			if err := bindParam(context, dest.Interface(), param); err != nil {
+				return nil, err
+			}
+		}
+		in[i] = dest.Elem()
+	}
+	return in, nil
+}
+
+func bindParam(context *beecontext.Context, dest interface{}, param *methodParam) error {
+	switch param.in {
+	case paramInPath:
+		name := param.name
+		if !strings.HasPrefix(name, ":") {
+			name = ":" + name
+		}
+		if !BConfig.RouterCaseSensitive {
+			name = strings.ToLower(name)
+		}
+		return context.Input.Bind(dest, name)
+	case paramInQuery:
+		return context.Input.Bind(dest, param.name)
+	case paramInHeader:
+		return context.Input.BindHeader(dest, param.name)
+	default:
+		return context.Input.BindBody(dest, BConfig.MaxMemory)
+	}
+}
+
+// isBodyType reports whether the argument of typ is decoded from body,
+// which is a struct except time.Time, a map, or a pointer to them.
+func isBodyType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct && typ != timeType || typ.Kind() == reflect.Map
+}
+
+// actionError is the response of a controller method returning an error.
+type actionError struct {
+	XMLName xml.Name `json:"-" xml:"error"`
+	Code    string   `json:"code,omitempty" xml:"code,attr,omitempty"`
+	Error   string   `json:"error" xml:",chardata"`
+}
+
+// serveResults serves the value returned by the controller method with ServeFormatted,
+// or the error response when it returns a non-nil error, whose status is 500 unless it's an AppError or registered.
+func serveResults(context *beecontext.Context, execController ControllerInterface, out []reflect.Value) {
+	if context.ResponseWriter.Started {
+		return
+	}
+	var (
+		result   interface{}
+		hasValue bool
+	)
+	for _, v := range out {
+		if v.Type().Implements(errorType) {
+			if !v.IsNil() {
+				err := v.Interface().(error)
+				status := http.StatusInternalServerError
+				ae := &actionError{Error: err.Error()}
+				if e := appError(err); e != nil {
+					status = e.Status
+					ae = &actionError{Code: e.Code, Error: e.Message}
+				}
+				if problemMode(context) {
+					writeProblem(context, errorProblem(status, err))
+					return
+				}
+				context.Output.SetStatus(status)
+				result = ae
+				hasValue = true
+				break
+			}
+			continue
+		}
+		if !hasValue {
+			result = v.Interface()
+			hasValue = true
+		}
+	}
+	if !hasValue {
+		return
+	}
+	if c, ok := execController.(interface {
+		ServeFormatted()
+	}); ok {
+		context.Input.SetData("json", result)
+		context.Input.SetData("xml", result)
+		c.ServeFormatted()
+		return
+	}
+	context.Output.JSON(result, false, false)
+}
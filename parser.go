@@ -50,6 +50,14 @@ var (
 
 const coomentPrefix = "commentsRouter_"
 
+// the builtin types bound from path or query by default
+var builtinTypes = map[string]bool{
+	"bool": true, "string": true, "byte": true, "rune": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
 func init() {
 	pkgLastupdate = make(map[string]int64)
 }
@@ -72,6 +80,7 @@ func parserPkg(pkgRealpath, pkgpath string) error {
 		return err
 	}
 	for _, pkg := range astPkgs {
+		types := packageTypes(pkg)
 		for _, fl := range pkg.Files {
 			for _, d := range fl.Decls {
 				switch specDecl := d.(type) {
@@ -79,7 +88,7 @@ func parserPkg(pkgRealpath, pkgpath string) error {
 					if specDecl.Recv != nil {
 						exp, ok := specDecl.Recv.List[0].Type.(*ast.StarExpr) // Check that the type is correct first beforing throwing to parser
 						if ok {
-							parserComments(specDecl.Doc, specDecl.Name.String(), fmt.Sprint(exp.X), pkgpath)
+							parserComments(specDecl, fmt.Sprint(exp.X), pkgpath, types)
 						}
 					}
 				}
@@ -91,7 +100,8 @@ func parserPkg(pkgRealpath, pkgpath string) error {
 	return nil
 }
 
-func parserComments(comments *ast.CommentGroup, funcName, controllerName, pkgpath string) error {
+func parserComments(f *ast.FuncDecl, controllerName, pkgpath string, types map[string]ast.Expr) error {
+	comments, funcName := f.Doc, f.Name.String()
 	if comments != nil && comments.List != nil {
 		for _, c := range comments.List {
 			t := strings.TrimSpace(strings.TrimLeft(c.Text, "//"))
@@ -122,6 +132,9 @@ func parserComments(comments *ast.CommentGroup, funcName, controllerName, pkgpat
 						cc.Params = append(cc.Params, map[string]string{strings.Join(kk[:len(kk)-1], ":"): kk[len(kk)-1]})
 					}
 				}
+				if f.Type.Params != nil && len(f.Type.Params.List) > 0 {
+					cc.Params = methodParamComments(f.Type.Params, cc.Router, cc.Params, types)
+				}
 				genInfoList[key] = append(genInfoList[key], cc)
 			}
 		}
@@ -129,6 +142,84 @@ func parserComments(comments *ast.CommentGroup, funcName, controllerName, pkgpat
 	return nil
 }
 
+// methodParamComments returns the sources of the method arguments in order,
+// the sources set in the comment, like [id:path q:query], are used first,
+// otherwise the argument is the body when it is a struct or map,
+// the query when it is a slice, or else a path param when router has it or the query.
+// the named types declared in types are resolved to their underlying types.
+func methodParamComments(fields *ast.FieldList, router string, params []map[string]string, types map[string]ast.Expr) []map[string]string {
+	sources := make(map[string]string)
+	for _, p := range params {
+		for k, v := range p {
+			sources[k] = v
+		}
+	}
+	var mps []map[string]string
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			in, ok := sources[name.Name]
+			if !ok {
+				switch paramKind(field.Type, types, make(map[string]bool)) {
+				case "body":
+					in = "body"
+				case "slice":
+					in = "query"
+				default:
+					if strings.Contains(router, ":"+name.Name) {
+						in = "path"
+					} else {
+						in = "query"
+					}
+				}
+			}
+			mps = append(mps, map[string]string{name.Name: in})
+		}
+	}
+	return mps
+}
+
+// paramKind returns the kind of the argument type expr, which is "scalar", "slice" or "body",
+// a pointer has the kind of its element, time.Time is a scalar,
+// and the types not declared in the package are decoded from the body.
+func paramKind(expr ast.Expr, types map[string]ast.Expr, seen map[string]bool) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if builtinTypes[t.Name] {
+			return "scalar"
+		}
+		if typ, ok := types[t.Name]; ok && !seen[t.Name] {
+			seen[t.Name] = true
+			return paramKind(typ, types, seen)
+		}
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok && x.Name == "time" && t.Sel.Name == "Time" {
+			return "scalar"
+		}
+	case *ast.StarExpr:
+		return paramKind(t.X, types, seen)
+	case *ast.ArrayType:
+		return "slice"
+	}
+	return "body"
+}
+
+// packageTypes returns the types declared in pkg by name.
+func packageTypes(pkg *ast.Package) map[string]ast.Expr {
+	types := make(map[string]ast.Expr)
+	for _, fl := range pkg.Files {
+		for _, d := range fl.Decls {
+			if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == token.TYPE {
+				for _, spec := range gd.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						types[ts.Name.Name] = ts.Type
+					}
+				}
+			}
+		}
+	}
+	return types
+}
+
 func genRouterCode() {
 	os.Mkdir(path.Join(AppPath, "routers"), 0755)
 	Info("generate router from comments")
@@ -156,7 +247,7 @@ func genRouterCode() {
 				params = "[]map[string]string{"
 				for _, p := range c.Params {
 					for k, v := range p {
-						params = params + `map[string]string{` + k + `:"` + v + `"},`
+						params = params + `map[string]string{"` + k + `":"` + v + `"},`
 					}
 				}
 				params = strings.TrimRight(params, ",") + "}"
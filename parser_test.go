@@ -0,0 +1,52 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+const paramsSource = `package controllers
+
+type UserID int64
+
+type Status UserID
+
+type Filter struct {
+	Name string
+}
+
+func (c *UserController) Show(id UserID, status *Status, since time.Time, tags []string, f Filter, m map[string]string, q string) {
+}
+`
+
+func TestMethodParamComments(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "user.go", paramsSource, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := packageTypes(&ast.Package{Name: "controllers", Files: map[string]*ast.File{"user.go": f}})
+	fn := f.Decls[len(f.Decls)-1].(*ast.FuncDecl)
+	params := methodParamComments(fn.Type.Params, "/user/:id", []map[string]string{{"q": "header"}}, types)
+	expected := "[map[id:path] map[status:query] map[since:query] map[tags:query] map[f:body] map[m:body] map[q:header]]"
+	if fmt.Sprint(params) != expected {
+		t.Errorf("expect %s, got %v", expected, params)
+	}
+}
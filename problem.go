@@ -0,0 +1,189 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cooleo/goweb/context"
+)
+
+// the error modes of BConfig.ErrorMode and Namespace.ErrorMode
+const (
+	// ErrorModeHTML renders the errors by ErrorMaps
+	ErrorModeHTML = "html"
+	// ErrorModeAuto renders the errors as application/problem+json for the clients accepting json
+	ErrorModeAuto = "auto"
+	// ErrorModeProblem always renders the errors as application/problem+json
+	ErrorModeProblem = "problem"
+)
+
+const problemJSON = "application/problem+json"
+
+// errorModeKey is the key of the Namespace error mode in the input data.
+type errorModeKey struct{}
+
+// Problem is the RFC 7807 problem details of an error response.
+// Extensions are marshalled as the members of the problem.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// NewProblem returns the Problem of status, whose title is the status text.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// With sets the extension member key of the problem.
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Error returns the detail of the problem, or its title.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// MarshalJSON marshals the problem members with the extensions.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	if p.Type != "" {
+		m["type"] = p.Type
+	}
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+// errorProblem returns the Problem of err with status,
+// the invalid fields of a BindError are the extension member "fields",
+// the code and details of an AppError are the members "code" and "details",
+// the other errors are detailed only in the dev mode.
+func errorProblem(status int, err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+	var be *context.BindError
+	if errors.As(err, &be) {
+		p = NewProblem(be.Status, be.Message)
+		if len(be.Fields) > 0 {
+			p.With("fields", be.Fields)
+		}
+		return p
+	}
+	if e := appError(err); e != nil {
+		p = NewProblem(e.Status, e.Message)
+		if e.Code != "" {
+			p.With("code", e.Code)
+		}
+		if e.Details != nil {
+			p.With("details", e.Details)
+		}
+		return p
+	}
+	// the details of the other errors may be internal
+	var detail string
+	if BConfig.RunMode == DEV {
+		detail = err.Error()
+	}
+	return NewProblem(status, detail)
+}
+
+// problemMode returns whether the errors of the request are rendered as problems,
+// by the error mode of its Namespace or BConfig.ErrorMode.
+func problemMode(ctx *context.Context) bool {
+	mode := BConfig.ErrorMode
+	if m, ok := ctx.Input.GetData(errorModeKey{}).(string); ok {
+		mode = m
+	}
+	switch mode {
+	case ErrorModeProblem:
+		return true
+	case ErrorModeAuto:
+		switch ctx.Input.NegotiateContentType("text/html", applicationJSON, problemJSON) {
+		case applicationJSON, problemJSON:
+			return true
+		}
+	}
+	return false
+}
+
+// writeProblem writes the problem as application/problem+json,
+// the instance is the request path if it's empty.
+func writeProblem(ctx *context.Context, p *Problem) {
+	if p.Instance == "" && ctx.Request != nil {
+		p.Instance = ctx.Request.URL.Path
+	}
+	content, err := json.Marshal(p)
+	if err != nil {
+		http.Error(ctx.ResponseWriter, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx.Output.Header("Content-Type", problemJSON)
+	ctx.Output.Header("X-Content-Type-Options", "nosniff")
+	ctx.Output.SetStatus(p.Status)
+	ctx.Output.Body(content)
+}
+
+// AbortProblem stops the controller and renders the problem as application/problem+json.
+// usage:
+//	c.AbortProblem(goweb.NewProblem(409, "the user exists").With("user", name))
+func (c *Controller) AbortProblem(p *Problem) {
+	writeProblem(c.Ctx, p)
+	panic(ErrAbort)
+}
+
+// AbortError stops the controller and renders err,
+// the status of an AppError, a *context.BindError or a registered error is kept, and the others are 500.
+// it's rendered as a problem in the problem error mode, or by ErrorMaps.
+// usage:
+//	if err := c.BindBody(&u, true); err != nil {
+//		c.AbortError(err)
+//	}
+func (c *Controller) AbortError(err error) {
+	e := appError(err)
+	if e == nil {
+		e = &AppError{Status: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError), Err: err}
+		if BConfig.RunMode == DEV {
+			e.Message = err.Error()
+		}
+	}
+	renderAppError(c.Ctx, e)
+	panic(ErrAbort)
+}
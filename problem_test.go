@@ -0,0 +1,79 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type ProblemController struct {
+	Controller
+}
+
+func (pc *ProblemController) Get() {
+	switch pc.GetString("case") {
+	case "abort":
+		pc.CustomAbort(http.StatusConflict, "the user exists")
+	case "bind":
+		var u bindUser
+		pc.Ctx.Request.Header.Set("Content-Type", "application/json")
+		pc.Ctx.Input.RequestBody = []byte(`{"age":200}`)
+		if err := pc.BindBody(&u, true); err != nil {
+			pc.AbortError(err)
+		}
+	case "panic":
+		panic("boom")
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	errorMode := BConfig.ErrorMode
+	BConfig.ErrorMode = ErrorModeAuto
+	defer func() { BConfig.ErrorMode = errorMode }()
+	handler := NewControllerRegister()
+	handler.Add("/problem", &ProblemController{})
+
+	tests := []struct {
+		url    string
+		accept string
+		status int
+		body   string
+	}{
+		{"/none", "application/json", 404, `{"instance":"/none","status":404,"title":"Not Found","type":"about:blank"}`},
+		{"/problem?case=abort", "application/json", 409, `"detail":"the user exists"`},
+		{"/problem?case=bind", "application/problem+json", 400, `"fields":{"Age":"Range is 1 to 140","Name":"Can not be empty"}`},
+		{"/problem?case=panic", "application/json", 500, `"status":500`},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.url, nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status || w.Header().Get("Content-Type") != "application/problem+json" || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s should render the problem %d %s, get %d %s", tt.url, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	r, _ := http.NewRequest("GET", "/problem?case=abort", nil)
+	r.Header.Set("Accept", "text/html,application/json;q=0.9")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Content-Type") == "application/problem+json" || w.Body.String() != "the user exists" {
+		t.Errorf("the html clients should get the raw abort body, get %s", w.Body.String())
+	}
+}
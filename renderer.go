@@ -0,0 +1,127 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/cooleo/goweb/context"
+)
+
+const textHTML = "text/html"
+
+// ErrNotAcceptable is returned by Controller.Respond when no renderer matches the Accept header.
+var ErrNotAcceptable = errors.New("goweb: no renderer is acceptable")
+
+// Renderer writes data to the response in its media type,
+// it sets the Content-Type header.
+type Renderer interface {
+	Render(ctx *context.Context, data interface{}) error
+}
+
+// RendererFunc is an adapter to use a function as Renderer.
+type RendererFunc func(ctx *context.Context, data interface{}) error
+
+// Render calls f(ctx, data).
+func (f RendererFunc) Render(ctx *context.Context, data interface{}) error {
+	return f(ctx, data)
+}
+
+var (
+	renderers     = make(map[string]Renderer)
+	rendererTypes []string
+)
+
+func init() {
+	AddRenderer(applicationJSON, RendererFunc(func(ctx *context.Context, data interface{}) error {
+		return ctx.Output.JSON(data, BConfig.RunMode != PROD, false)
+	}))
+	AddRenderer(applicationXML, RendererFunc(func(ctx *context.Context, data interface{}) error {
+		return ctx.Output.XML(data, BConfig.RunMode != PROD)
+	}))
+	AddRenderer(textXML, RendererFunc(func(ctx *context.Context, data interface{}) error {
+		content, err := xml.Marshal(data)
+		if err != nil {
+			http.Error(ctx.ResponseWriter, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		ctx.Output.Header("Content-Type", "text/xml; charset=utf-8")
+		return ctx.Output.Body(content)
+	}))
+}
+
+// AddRenderer registers the Renderer of mediaType for Controller.Respond,
+// it replaces the Renderer registered for mediaType.
+// the media types are preferred in the registered order when the client accepts them equally,
+// the default ones are application/json, application/xml and text/xml.
+// usage:
+//	goweb.AddRenderer("text/csv", goweb.RendererFunc(func(ctx *context.Context, data interface{}) error {
+//		ctx.Output.Header("Content-Type", "text/csv; charset=utf-8")
+//		return ctx.Output.Body(toCSV(data))
+//	}))
+func AddRenderer(mediaType string, r Renderer) *App {
+	mediaType = strings.ToLower(mediaType)
+	if _, ok := renderers[mediaType]; !ok {
+		rendererTypes = append(rendererTypes, mediaType)
+	}
+	renderers[mediaType] = r
+	return BeeApp
+}
+
+// Respond renders data with the renderer of the media type the client accepts best,
+// and sets the Vary header to Accept.
+// text/html is offered after the registered renderers if the controller has a template,
+// it's rendered from the template with c.Data unless a renderer of text/html is registered.
+// it renders 406 and returns ErrNotAcceptable if no media type is acceptable.
+// usage:
+//	func (c *UserController) Get() {
+//		c.Data["User"] = user
+//		c.TplName = "user.tpl"
+//		c.Respond(user)
+//	}
+func (c *Controller) Respond(data interface{}) error {
+	c.EnableRender = false
+	offers := rendererTypes
+	if _, ok := renderers[textHTML]; !ok && c.TplName != "" {
+		offers = append(offers[:len(offers):len(offers)], textHTML)
+	}
+	addVary(c.Ctx, "Accept")
+	mediaType := c.Ctx.Input.NegotiateContentType(offers...)
+	if mediaType == "" {
+		exception("406", c.Ctx)
+		return ErrNotAcceptable
+	}
+	if r, ok := renderers[mediaType]; ok {
+		return r.Render(c.Ctx, data)
+	}
+	c.EnableRender = true
+	return c.Render()
+}
+
+// addVary adds the field to the Vary header of the response if it isn't there.
+func addVary(ctx *context.Context, field string) {
+	header := ctx.ResponseWriter.Header()
+	for _, v := range header["Vary"] {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cooleo/goweb/context"
+)
+
+type RespondController struct {
+	Controller
+}
+
+type respondData struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (rc *RespondController) Get() {
+	rc.Respond(&respondData{Name: "goweb"})
+}
+
+func (rc *RespondController) Formatted() {
+	rc.Data["json"] = &respondData{Name: "goweb"}
+	rc.Data["xml"] = &respondData{Name: "goweb"}
+	rc.ServeFormatted()
+}
+
+func TestRespond(t *testing.T) {
+	AddRenderer("text/csv", RendererFunc(func(ctx *context.Context, data interface{}) error {
+		ctx.Output.Header("Content-Type", "text/csv; charset=utf-8")
+		return ctx.Output.Body([]byte("name\n" + data.(*respondData).Name + "\n"))
+	}))
+	defer func() {
+		delete(renderers, "text/csv")
+		rendererTypes = rendererTypes[:len(rendererTypes)-1]
+	}()
+	handler := NewControllerRegister()
+	handler.Add("/respond", &RespondController{})
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"", 200, "application/json; charset=utf-8", "goweb"},
+		{"text/csv;q=0.9, application/json;q=0.5", 200, "text/csv; charset=utf-8", "name\ngoweb\n"},
+		{"text/xml", 200, "text/xml; charset=utf-8", "goweb"},
+		{"image/png", 406, "", ""},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", "/respond", nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status || w.Header().Get("Vary") != "Accept" {
+			t.Errorf("Accept %q should respond %d with Vary, get %d %v", tt.accept, tt.status, w.Code, w.Header())
+		}
+		if tt.contentType != "" && w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("Accept %q should respond %s, get %s", tt.accept, tt.contentType, w.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("Accept %q should respond %q, get %q", tt.accept, tt.body, w.Body.String())
+		}
+	}
+
+	// ServeFormatted only serves xml for the exact Accept
+	handler.Add("/formatted", &RespondController{}, "get:Formatted")
+	for accept, contentType := range map[string]string{
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": "application/json; charset=utf-8",
+		"application/xml": "application/xml; charset=utf-8",
+	} {
+		r, _ := http.NewRequest("GET", "/formatted", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Header().Get("Content-Type") != contentType {
+			t.Errorf("ServeFormatted with Accept %q should respond %s, get %s", accept, contentType, w.Header().Get("Content-Type"))
+		}
+	}
+}
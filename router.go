@@ -15,16 +15,20 @@
 package goweb
 
 import (
+	gocontext "context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
-	"runtime"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	beecontext "github.com/cooleo/goweb/context"
@@ -67,7 +71,8 @@ var (
 		"GetFloat", "GetFile", "SaveToFile", "StartSession", "SetSession", "GetSession",
 		"DelSession", "SessionRegenerateID", "DestroySession", "IsAjax", "GetSecureCookie",
 		"SetSecureCookie", "XsrfToken", "CheckXsrfCookie", "XsrfFormHtml",
-		"GetControllerAndAction", "ServeFormatted"}
+		"GetControllerAndAction", "ServeFormatted", "Context", "EventStream", "ServeJSONArray",
+		"Respond", "BindBody", "AbortProblem", "AbortError"}
 
 	urlPlaceholder = "{{placeholder}}"
 	// DefaultAccessLogFilter will skip the accesslog if return true
@@ -108,22 +113,31 @@ type controllerInfo struct {
 	handler        http.Handler
 	runFunction    FilterFunc
 	routerType     int
+	chains         []FilterChain
+	name           string
+	timeout        *routeTimeout
+	params         []*methodParam
+	pathParams     []string
+	// the Namespace router this one is copied from
+	origin *controllerInfo
 }
 
 // ControllerRegister containers registered router rules, controller handlers and filters.
+// the routers can be added and removed while serving,
+// a request keeps using the routers registered when it started.
 type ControllerRegister struct {
-	routers      map[string]*Tree
-	enableFilter bool
-	filters      map[int][]*FilterRouter
-	pool         sync.Pool
+	table atomic.Value // *routerTable
+	mu    sync.Mutex
+	last  *controllerInfo
+	pool  sync.Pool
+	// the host pattern of the ControllerRegister returned by Host
+	host string
 }
 
 // NewControllerRegister returns a new ControllerRegister.
 func NewControllerRegister() *ControllerRegister {
-	cr := &ControllerRegister{
-		routers: make(map[string]*Tree),
-		filters: make(map[int][]*FilterRouter),
-	}
+	cr := &ControllerRegister{}
+	cr.table.Store(newRouterTable())
 	cr.pool.New = func() interface{} {
 		return beecontext.NewContext()
 	}
@@ -141,6 +155,13 @@ func NewControllerRegister() *ControllerRegister {
 //	Add("/api",&RestController{},"get,post:ApiFunc"
 //	Add("/simple",&SimpleController{},"get:GetFunc;post:PostFunc")
 func (p *ControllerRegister) Add(pattern string, c ControllerInterface, mappingMethods ...string) {
+	p.AddWithChain(pattern, c, nil, mappingMethods...)
+}
+
+// AddWithChain is the same as Add, but the controller only runs through the given FilterChain.
+// usage:
+//	AddWithChain("/admin",&AdminController{},[]FilterChain{Auth},"get:List")
+func (p *ControllerRegister) AddWithChain(pattern string, c ControllerInterface, chains []FilterChain, mappingMethods ...string) {
 	reflectVal := reflect.ValueOf(c)
 	t := reflect.Indirect(reflectVal).Type()
 	methods := make(map[string]string)
@@ -171,34 +192,33 @@ func (p *ControllerRegister) Add(pattern string, c ControllerInterface, mappingM
 	route.methods = methods
 	route.routerType = routerTypegoweb
 	route.controllerType = t
-	if len(methods) == 0 {
-		for _, m := range HTTPMETHOD {
-			p.addToRouter(m, pattern, route)
-		}
-	} else {
-		for k := range methods {
-			if k == "*" {
-				for _, m := range HTTPMETHOD {
-					p.addToRouter(m, pattern, route)
+	route.chains = chains
+	route.pathParams = patternParams(pattern)
+	p.update(func(t *routerTable) {
+		if len(methods) == 0 {
+			for _, m := range HTTPMETHOD {
+				p.addToRouter(t, m, pattern, route)
+			}
+		} else {
+			for k := range methods {
+				if k == "*" {
+					for _, m := range HTTPMETHOD {
+						p.addToRouter(t, m, pattern, route)
+					}
+				} else {
+					p.addToRouter(t, k, pattern, route)
 				}
-			} else {
-				p.addToRouter(k, pattern, route)
 			}
 		}
-	}
+	})
 }
 
-func (p *ControllerRegister) addToRouter(method, pattern string, r *controllerInfo) {
+func (p *ControllerRegister) addToRouter(t *routerTable, method, pattern string, r *controllerInfo) {
+	p.last = r
 	if !BConfig.RouterCaseSensitive {
 		pattern = strings.ToLower(pattern)
 	}
-	if t, ok := p.routers[method]; ok {
-		t.AddRouter(pattern, r)
-	} else {
-		t := NewTree()
-		t.AddRouter(pattern, r)
-		p.routers[method] = t
-	}
+	t.addRouter(method, pattern, r)
 }
 
 // Include only when the Runmode is dev will generate router file in the router/auto.go from the controller
@@ -238,6 +258,12 @@ func (p *ControllerRegister) Include(cList ...ControllerInterface) {
 		if comm, ok := GlobalControllerRouter[key]; ok {
 			for _, a := range comm {
 				p.Add(a.Router, c, strings.Join(a.AllowHTTPMethods, ",")+":"+a.Method)
+				if len(a.Params) > 0 && reflectVal.MethodByName(a.Method).Type().NumIn() > 0 {
+					mps := parseMethodParams(a.Params)
+					p.updateLast("set params", func(t *routerTable, r *controllerInfo) {
+						r.params = mps
+					})
+				}
 			}
 		}
 	}
@@ -248,8 +274,8 @@ func (p *ControllerRegister) Include(cList ...ControllerInterface) {
 //    Get("/", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func (p *ControllerRegister) Get(pattern string, f FilterFunc) {
-	p.AddMethod("get", pattern, f)
+func (p *ControllerRegister) Get(pattern string, f FilterFunc, chains ...FilterChain) {
+	p.AddMethod("get", pattern, f, chains...)
 }
 
 // Post add post method
@@ -257,8 +283,8 @@ func (p *ControllerRegister) Get(pattern string, f FilterFunc) {
 //    Post("/api", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func (p *ControllerRegister) Post(pattern string, f FilterFunc) {
-	p.AddMethod("post", pattern, f)
+func (p *ControllerRegister) Post(pattern string, f FilterFunc, chains ...FilterChain) {
+	p.AddMethod("post", pattern, f, chains...)
 }
 
 // Put add put method
@@ -266,8 +292,8 @@ func (p *ControllerRegister) Post(pattern string, f FilterFunc) {
 //    Put("/api/:id", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func (p *ControllerRegister) Put(pattern string, f FilterFunc) {
-	p.AddMethod("put", pattern, f)
+func (p *ControllerRegister) Put(pattern string, f FilterFunc, chains ...FilterChain) {
+	p.AddMethod("put", pattern, f, chains...)
 }
 
 // Delete add delete method
@@ -275,8 +301,8 @@ func (p *ControllerRegister) Put(pattern string, f FilterFunc) {
 //    Delete("/api/:id", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func (p *ControllerRegister) Delete(pattern string, f FilterFunc) {
-	p.AddMethod("delete", pattern, f)
+func (p *ControllerRegister) Delete(pattern string, f FilterFunc, chains ...FilterChain) {
+	p.AddMethod("delete", pattern, f, chains...)
 }
 
 // Head add head method
@@ -284,8 +310,8 @@ func (p *ControllerRegister) Delete(pattern string, f FilterFunc) {
 //    Head("/api/:id", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func (p *ControllerRegister) Head(pattern string, f FilterFunc) {
-	p.AddMethod("head", pattern, f)
+func (p *ControllerRegister) Head(pattern string, f FilterFunc, chains ...FilterChain) {
+	p.AddMethod("head", pattern, f, chains...)
 }
 
 // Patch add patch method
@@ -293,8 +319,8 @@ func (p *ControllerRegister) Head(pattern string, f FilterFunc) {
 //    Patch("/api/:id", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func (p *ControllerRegister) Patch(pattern string, f FilterFunc) {
-	p.AddMethod("patch", pattern, f)
+func (p *ControllerRegister) Patch(pattern string, f FilterFunc, chains ...FilterChain) {
+	p.AddMethod("patch", pattern, f, chains...)
 }
 
 // Options add options method
@@ -302,8 +328,8 @@ func (p *ControllerRegister) Patch(pattern string, f FilterFunc) {
 //    Options("/api/:id", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func (p *ControllerRegister) Options(pattern string, f FilterFunc) {
-	p.AddMethod("options", pattern, f)
+func (p *ControllerRegister) Options(pattern string, f FilterFunc, chains ...FilterChain) {
+	p.AddMethod("options", pattern, f, chains...)
 }
 
 // Any add all method
@@ -311,8 +337,8 @@ func (p *ControllerRegister) Options(pattern string, f FilterFunc) {
 //    Any("/api/:id", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func (p *ControllerRegister) Any(pattern string, f FilterFunc) {
-	p.AddMethod("*", pattern, f)
+func (p *ControllerRegister) Any(pattern string, f FilterFunc, chains ...FilterChain) {
+	p.AddMethod("*", pattern, f, chains...)
 }
 
 // AddMethod add http method router
@@ -320,7 +346,8 @@ func (p *ControllerRegister) Any(pattern string, f FilterFunc) {
 //    AddMethod("get","/api/:id", func(ctx *context.Context){
 //          ctx.Output.Body("hello world")
 //    })
-func (p *ControllerRegister) AddMethod(method, pattern string, f FilterFunc) {
+// the optional chains wrap f in declared order.
+func (p *ControllerRegister) AddMethod(method, pattern string, f FilterFunc, chains ...FilterChain) {
 	method = strings.ToUpper(method)
 	if _, ok := HTTPMETHOD[method]; method != "*" && !ok {
 		panic("not support http method: " + method)
@@ -329,6 +356,7 @@ func (p *ControllerRegister) AddMethod(method, pattern string, f FilterFunc) {
 	route.pattern = pattern
 	route.routerType = routerTypeRESTFul
 	route.runFunction = f
+	route.chains = chains
 	methods := make(map[string]string)
 	if method == "*" {
 		for _, val := range HTTPMETHOD {
@@ -338,31 +366,57 @@ func (p *ControllerRegister) AddMethod(method, pattern string, f FilterFunc) {
 		methods[method] = method
 	}
 	route.methods = methods
-	for k := range methods {
-		if k == "*" {
-			for _, m := range HTTPMETHOD {
-				p.addToRouter(m, pattern, route)
+	p.update(func(t *routerTable) {
+		for k := range methods {
+			if k == "*" {
+				for _, m := range HTTPMETHOD {
+					p.addToRouter(t, m, pattern, route)
+				}
+			} else {
+				p.addToRouter(t, k, pattern, route)
 			}
-		} else {
-			p.addToRouter(k, pattern, route)
 		}
-	}
+	})
 }
 
 // Handler add user defined Handler
+// options can be a bool to match all the sub paths of pattern,
+// and FilterChain to wrap the handler.
 func (p *ControllerRegister) Handler(pattern string, h http.Handler, options ...interface{}) {
 	route := &controllerInfo{}
 	route.pattern = pattern
 	route.routerType = routerTypeHandler
 	route.handler = h
-	if len(options) > 0 {
-		if _, ok := options[0].(bool); ok {
+	for _, o := range options {
+		switch v := o.(type) {
+		case bool:
 			pattern = path.Join(pattern, "?:all(.*)")
+		case FilterChain:
+			route.chains = append(route.chains, v)
+		case func(FilterFunc) FilterFunc:
+			route.chains = append(route.chains, v)
 		}
 	}
-	for _, m := range HTTPMETHOD {
-		p.addToRouter(m, pattern, route)
-	}
+	p.update(func(t *routerTable) {
+		for _, m := range HTTPMETHOD {
+			p.addToRouter(t, m, pattern, route)
+		}
+	})
+}
+
+// Name sets the name of the last added router, so that URLFor can build its url by name.
+// A duplicated name panics in dev mode, otherwise the first router keeps the name.
+// usage:
+//	Add("/user/:id",&UserController{},"get:Show")
+//	Name("user.show")
+//	URLFor("user.show", ":id", 1) -> /user/1
+func (p *ControllerRegister) Name(name string) *ControllerRegister {
+	p.updateLast("name "+name, func(t *routerTable, r *controllerInfo) {
+		if t.addName(name, r) {
+			r.name = name
+		}
+	})
+	return p
 }
 
 // AddAuto router to ControllerRegister.
@@ -384,57 +438,108 @@ func (p *ControllerRegister) AddAutoPrefix(prefix string, c ControllerInterface)
 	rt := reflectVal.Type()
 	ct := reflect.Indirect(reflectVal).Type()
 	controllerName := strings.TrimSuffix(ct.Name(), "Controller")
-	for i := 0; i < rt.NumMethod(); i++ {
-		if !utils.InSlice(rt.Method(i).Name, exceptMethod) {
-			route := &controllerInfo{}
-			route.routerType = routerTypegoweb
-			route.methods = map[string]string{"*": rt.Method(i).Name}
-			route.controllerType = ct
-			pattern := path.Join(prefix, strings.ToLower(controllerName), strings.ToLower(rt.Method(i).Name), "*")
-			patternInit := path.Join(prefix, controllerName, rt.Method(i).Name, "*")
-			patternFix := path.Join(prefix, strings.ToLower(controllerName), strings.ToLower(rt.Method(i).Name))
-			patternFixInit := path.Join(prefix, controllerName, rt.Method(i).Name)
-			route.pattern = pattern
-			for _, m := range HTTPMETHOD {
-				p.addToRouter(m, pattern, route)
-				p.addToRouter(m, patternInit, route)
-				p.addToRouter(m, patternFix, route)
-				p.addToRouter(m, patternFixInit, route)
+	p.update(func(t *routerTable) {
+		for i := 0; i < rt.NumMethod(); i++ {
+			if !utils.InSlice(rt.Method(i).Name, exceptMethod) {
+				route := &controllerInfo{}
+				route.routerType = routerTypegoweb
+				route.methods = map[string]string{"*": rt.Method(i).Name}
+				route.controllerType = ct
+				pattern := path.Join(prefix, strings.ToLower(controllerName), strings.ToLower(rt.Method(i).Name), "*")
+				patternInit := path.Join(prefix, controllerName, rt.Method(i).Name, "*")
+				patternFix := path.Join(prefix, strings.ToLower(controllerName), strings.ToLower(rt.Method(i).Name))
+				patternFixInit := path.Join(prefix, controllerName, rt.Method(i).Name)
+				route.pattern = pattern
+				route.pathParams = patternParams(pattern)
+				for _, m := range HTTPMETHOD {
+					p.addToRouter(t, m, pattern, route)
+					p.addToRouter(t, m, patternInit, route)
+					p.addToRouter(t, m, patternFix, route)
+					p.addToRouter(t, m, patternFixInit, route)
+				}
 			}
 		}
-	}
+	})
 }
 
 // InsertFilter Add a FilterFunc with pattern rule and action constant.
 // The bool params is for setting the returnOnOutput value (false allows multiple filters to execute)
 func (p *ControllerRegister) InsertFilter(pattern string, pos int, filter FilterFunc, params ...bool) error {
+	if len(params) == 0 {
+		return p.InsertFilterWithOptions(pattern, pos, filter)
+	}
+	return p.InsertFilterWithOptions(pattern, pos, filter, FilterReturnOnOutput(params[0]))
+}
 
+// InsertFilterWithOptions is the same as InsertFilter, with the name, priority
+// and returnOnOutput of the filter set by options.
+// it returns an error if the name is already used, or p is returned by Host.
+// usage:
+//	InsertFilterWithOptions("/*", BeforeRouter, Auth, FilterName("auth"), FilterPriority(-10))
+//	InsertFilterWithOptions("/*", BeforeRouter, CORS, FilterName("cors"))
+func (p *ControllerRegister) InsertFilterWithOptions(pattern string, pos int, filter FilterFunc, opts ...FilterOption) error {
+	if p.host != "" {
+		return fmt.Errorf("filters can't be inserted into the routers of host %s, insert them into the app", p.host)
+	}
 	mr := new(FilterRouter)
 	mr.tree = NewTree()
 	mr.pattern = pattern
 	mr.filterFunc = filter
+	mr.returnOnOutput = true
+	for _, o := range opts {
+		o(mr)
+	}
 	if !BConfig.RouterCaseSensitive {
 		pattern = strings.ToLower(pattern)
 	}
-	if len(params) == 0 {
-		mr.returnOnOutput = true
-	} else {
-		mr.returnOnOutput = params[0]
-	}
 	mr.tree.AddRouter(pattern, true)
-	return p.insertFilterRouter(pos, mr)
+	var err error
+	p.update(func(t *routerTable) {
+		if mr.name != "" {
+			for _, filterList := range t.filters {
+				for _, f := range filterList {
+					if f.name == mr.name {
+						err = fmt.Errorf("filter name %s is already used", mr.name)
+						return
+					}
+				}
+			}
+		}
+		t.insertFilterRouter(pos, mr)
+	})
+	return err
 }
 
-// add Filter into
-func (p *ControllerRegister) insertFilterRouter(pos int, mr *FilterRouter) error {
-	p.filters[pos] = append(p.filters[pos], mr)
-	p.enableFilter = true
-	return nil
+// RemoveFilter removes the filters named name by FilterName from all the positions.
+// it returns false if there is no such filter.
+func (p *ControllerRegister) RemoveFilter(name string) bool {
+	var removed bool
+	p.update(func(t *routerTable) {
+		removed = t.removeFilters(func(mr *FilterRouter) bool {
+			return mr.name == name
+		})
+	})
+	return removed
+}
+
+// Filters returns the inserted filters of each position in execution order.
+func (p *ControllerRegister) Filters() map[int][]FilterInfo {
+	filters := make(map[int][]FilterInfo)
+	for pos, filterList := range p.load().filters {
+		for _, f := range filterList {
+			filters[pos] = append(filters[pos], f.info())
+		}
+	}
+	return filters
 }
 
 // URLFor does another controller handler in this request function.
 // it can access any controller method.
+// endpoint is the name of a router first, see Name, then path.controller.method.
 func (p *ControllerRegister) URLFor(endpoint string, values ...interface{}) string {
+	if url, ok := p.urlForName(endpoint, values...); ok {
+		return url
+	}
 	paths := strings.Split(endpoint, ".")
 	if len(paths) <= 1 {
 		Warn("urlfor endpoint must like path.controller.method")
@@ -444,6 +549,41 @@ func (p *ControllerRegister) URLFor(endpoint string, values ...interface{}) stri
 		Warn("urlfor params must key-value pair")
 		return ""
 	}
+	params := urlParams(values)
+	controllName := strings.Join(paths[:len(paths)-1], "/")
+	methodName := paths[len(paths)-1]
+	table := p.load()
+	for m, t := range table.routers {
+		ok, url := p.geturl(t, "/", controllName, methodName, params, m)
+		if ok {
+			return url
+		}
+	}
+	for _, h := range table.hostRouters {
+		for m, t := range h.handlers.load().routers {
+			hostParams := make(map[string]string)
+			for k, v := range params {
+				if utils.InSlice(k, h.params) {
+					hostParams[k] = v
+					delete(params, k)
+				}
+			}
+			ok, url := p.geturl(t, "/", controllName, methodName, params, m)
+			for k, v := range hostParams {
+				params[k] = v
+			}
+			if ok {
+				if host, ok := h.build(hostParams); ok {
+					return hostScheme() + "://" + host + url
+				}
+				return url
+			}
+		}
+	}
+	return ""
+}
+
+func urlParams(values []interface{}) map[string]string {
 	params := make(map[string]string)
 	if len(values) > 0 {
 		key := ""
@@ -455,15 +595,168 @@ func (p *ControllerRegister) URLFor(endpoint string, values ...interface{}) stri
 			}
 		}
 	}
-	controllName := strings.Join(paths[:len(paths)-1], "/")
-	methodName := paths[len(paths)-1]
-	for m, t := range p.routers {
-		ok, url := p.geturl(t, "/", controllName, methodName, params, m)
+	return params
+}
+
+// urlForName builds the url of the router named name.
+// the build errors panic in dev mode, otherwise they are logged and the url is empty.
+func (p *ControllerRegister) urlForName(name string, values ...interface{}) (string, bool) {
+	var (
+		r *controllerInfo
+		h *hostRouter
+	)
+	table := p.load()
+	if r = table.names[name]; r == nil {
+		for _, hr := range table.hostRouters {
+			if r = hr.handlers.load().names[name]; r != nil {
+				h = hr
+				break
+			}
+		}
+	}
+	if r == nil {
+		return "", false
+	}
+	if len(values)%2 != 0 {
+		Warn("urlfor params must key-value pair")
+		return "", true
+	}
+	params := urlParams(values)
+	hostParams := make(map[string]string)
+	if h != nil {
+		for _, k := range h.params {
+			if v, ok := params[k]; ok {
+				hostParams[k] = v
+				delete(params, k)
+			}
+		}
+	}
+	url, err := buildURL(r.pattern, params)
+	if err != nil {
+		err = fmt.Errorf("urlfor %s: %v", name, err)
+		if BConfig.RunMode == DEV {
+			panic(err)
+		}
+		Warn(err)
+		return "", true
+	}
+	if h != nil {
+		if host, ok := h.build(hostParams); ok {
+			return hostScheme() + "://" + host + url, true
+		}
+	}
+	return url, true
+}
+
+// buildURL replaces the params of the router pattern with their values,
+// the params which aren't in the pattern are appended as query string.
+// "/user/:id:int/?:tab", {":id": "1", "v": "2"} -> "/user/1?v=2"
+func buildURL(pattern string, params map[string]string) (string, error) {
+	var segments []string
+	for _, seg := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		switch seg {
+		case "*":
+			v, ok := params[":splat"]
+			if !ok {
+				return "", fmt.Errorf("missing param :splat of %s", pattern)
+			}
+			delete(params, ":splat")
+			segments = append(segments, v)
+			continue
+		case "*.*":
+			p, ok := params[":path"]
+			e, isok := params[":ext"]
+			if !ok || !isok {
+				return "", fmt.Errorf("missing param :path or :ext of %s", pattern)
+			}
+			delete(params, ":path")
+			delete(params, ":ext")
+			segments = append(segments, p+"."+e)
+			continue
+		}
+		v, ok, err := buildSegment(seg, params)
+		if err != nil {
+			return "", fmt.Errorf("%v of %s", err, pattern)
+		}
 		if ok {
-			return url
+			segments = append(segments, v)
 		}
 	}
-	return ""
+	return "/" + strings.Join(segments, "/") + toURL(params), nil
+}
+
+// buildSegment returns false if the optional param of seg has no value.
+func buildSegment(seg string, params map[string]string) (string, bool, error) {
+	var (
+		out      []byte
+		optional bool
+	)
+	for i := 0; i < len(seg); {
+		switch {
+		case seg[i] == '\\' && i+1 < len(seg):
+			out = append(out, seg[i+1])
+			i += 2
+		case seg[i] == '?' && i+1 < len(seg) && seg[i+1] == ':':
+			optional = true
+			i++
+		case seg[i] == ':':
+			j := i + 1
+			for j < len(seg) && isParamChar(seg[j]) {
+				j++
+			}
+			name := seg[i:j]
+			var c *paramConstraint
+			if j < len(seg) && seg[j] == ':' {
+				if pc, n := parseConstraint(seg[j+1:]); pc != nil {
+					c = pc
+					j += 1 + n
+				} else if kind := constraintNameRegex.FindString(seg[j+1:]); kind == "int" || kind == "string" {
+					j += 1 + len(kind)
+				}
+			}
+			if j < len(seg) && seg[j] == '(' {
+				for depth := 0; j < len(seg); j++ {
+					if seg[j] == '(' {
+						depth++
+					} else if seg[j] == ')' {
+						if depth--; depth == 0 {
+							j++
+							break
+						}
+					}
+				}
+			}
+			v, ok := params[name]
+			if !ok {
+				if optional {
+					return "", false, nil
+				}
+				return "", false, fmt.Errorf("missing param %s", name)
+			}
+			if c != nil && !c.check(v) {
+				return "", false, fmt.Errorf("param %s value %s doesn't match constraint %s", name, v, c.name)
+			}
+			delete(params, name)
+			out = append(out, v...)
+			i = j
+		default:
+			out = append(out, seg[i])
+			i++
+		}
+	}
+	return string(out), true, nil
+}
+
+func isParamChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// hostScheme returns the scheme used by URLFor to build absolute URLs for host-bound routes.
+func hostScheme() string {
+	if BConfig.Listen.EnableHTTPS && !BConfig.Listen.EnableHTTP {
+		return "https"
+	}
+	return "http"
 }
 
 func (p *ControllerRegister) geturl(t *Tree, url, controllName, methodName string, params map[string]string, httpMethod string) (bool, string) {
@@ -577,17 +870,32 @@ func (p *ControllerRegister) geturl(t *Tree, url, controllName, methodName strin
 	return false, ""
 }
 
-func (p *ControllerRegister) execFilter(context *beecontext.Context, pos int, urlPath string) (started bool) {
-	if p.enableFilter {
-		if l, ok := p.filters[pos]; ok {
+// execFilter runs the filters of pos matching urlPath, it returns true if the request is stopped.
+// a filter can stop the request with AbortWith, skip the remaining filters with SkipFilters,
+// or run the remaining filters of pos regardless of the written response with Next,
+// otherwise the filters are stopped once the response is written if they are returnOnOutput
+// and stopOnOutput is true.
+func (t *routerTable) execFilter(context *beecontext.Context, pos int, urlPath string, stopOnOutput bool) (started bool) {
+	if t.enableFilter {
+		if l, ok := t.filters[pos]; ok {
 			for _, filterR := range l {
-				if filterR.returnOnOutput && context.ResponseWriter.Started {
+				if stopOnOutput && filterR.returnOnOutput && context.ResponseWriter.Started {
 					return true
 				}
 				if ok := filterR.ValidRouter(urlPath, context); ok {
+					context.SetFilterAction(beecontext.FilterDefault)
 					filterR.filterFunc(context)
+					switch context.FilterAction() {
+					case beecontext.FilterAbort:
+						return true
+					case beecontext.FilterSkip:
+						return false
+					case beecontext.FilterNext:
+						// the response written so far doesn't stop the remaining filters of pos
+						stopOnOutput = false
+					}
 				}
-				if filterR.returnOnOutput && context.ResponseWriter.Started {
+				if stopOnOutput && filterR.returnOnOutput && context.ResponseWriter.Started {
 					return true
 				}
 			}
@@ -604,9 +912,14 @@ func (p *ControllerRegister) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 		findRouter bool
 		runMethod  string
 		routerInfo *controllerInfo
+		timedOut   bool
+		executed   bool
+		table      = p.load()
 	)
+	ctx, cancel := requestContext(r)
+	defer cancel()
 	context := p.pool.Get().(*beecontext.Context)
-	context.Reset(rw, r)
+	context.Reset(rw, r.WithContext(ctx))
 
 	defer p.pool.Put(context)
 	defer p.recoverPanic(context)
@@ -631,14 +944,20 @@ func (p *ControllerRegister) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 	}
 
 	// filter for static file
-	if p.execFilter(context, BeforeStatic, urlPath) {
-		goto Admin
+	if table.execFilter(context, BeforeStatic, urlPath, true) {
+		goto After
 	}
 
 	serverStaticRouter(context)
 	if context.ResponseWriter.Started {
 		findRouter = true
-		goto Admin
+		goto After
+	}
+
+	// redirect to the canonical path of the policies
+	if cp := canonicalPath(r.URL.Path); cp != r.URL.Path {
+		redirectPath(context, cp)
+		goto After
 	}
 
 	if r.Method != "GET" && r.Method != "HEAD" {
@@ -664,152 +983,109 @@ func (p *ControllerRegister) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 		}()
 	}
 
-	if p.execFilter(context, BeforeRouter, urlPath) {
-		goto Admin
+	if table.execFilter(context, BeforeRouter, urlPath, true) {
+		goto After
 	}
 
 	if !findRouter {
-		httpMethod := r.Method
-		if t, ok := p.routers[httpMethod]; ok {
-			runObject := t.Match(urlPath, context)
-			if r, ok := runObject.(*controllerInfo); ok {
-				routerInfo = r
-				findRouter = true
-				if splat := context.Input.Param(":splat"); splat != "" {
-					for k, v := range strings.Split(splat, "/") {
-						context.Input.SetParam(strconv.Itoa(k), v)
-					}
+		if routerInfo = table.matchRouter(r.Method, urlPath, context); routerInfo != nil {
+			findRouter = true
+			context.Input.SetData(routerPatternKey{}, routerInfo.pattern)
+			if splat := context.Input.Param(":splat"); splat != "" {
+				for k, v := range strings.Split(splat, "/") {
+					context.Input.SetParam(strconv.Itoa(k), v)
 				}
 			}
 		}
-
 	}
 
-	//if no matches to url, throw a not found exception
+	//if no matches to url, answer OPTIONS or throw a method not allowed exception
+	//when other methods match it, else throw a not found exception
 	if !findRouter {
+		if allow := table.allowMethods(urlPath, context); len(allow) > 0 {
+			context.Output.Header("Allow", strings.Join(allow, ", "))
+			if r.Method == "OPTIONS" {
+				context.Output.SetStatus(http.StatusOK)
+			} else {
+				exception("405", context)
+			}
+			goto After
+		}
 		exception("404", context)
-		goto Admin
+		goto After
 	}
 
 	if findRouter {
 		//execute middleware filters
-		if p.execFilter(context, BeforeExec, urlPath) {
-			goto Admin
+		if table.execFilter(context, BeforeExec, urlPath, true) {
+			goto After
 		}
-		isRunnable := false
-		if routerInfo != nil {
-			if routerInfo.routerType == routerTypeRESTFul {
-				if _, ok := routerInfo.methods[r.Method]; ok {
-					isRunnable = true
-					routerInfo.runFunction(context)
-				} else {
-					exception("405", context)
-					goto Admin
-				}
-			} else if routerInfo.routerType == routerTypeHandler {
-				isRunnable = true
-				routerInfo.handler.ServeHTTP(rw, r)
-			} else {
-				runRouter = routerInfo.controllerType
-				method := r.Method
-				if r.Method == "POST" && context.Input.Query("_method") == "PUT" {
-					method = "PUT"
+		if routerInfo.routerType == routerTypeRESTFul {
+			if _, ok := routerInfo.methods[r.Method]; !ok {
+				var allow []string
+				for m := range routerInfo.methods {
+					allow = append(allow, m)
 				}
-				if r.Method == "POST" && context.Input.Query("_method") == "DELETE" {
-					method = "DELETE"
-				}
-				if m, ok := routerInfo.methods[method]; ok {
-					runMethod = m
-				} else if m, ok = routerInfo.methods["*"]; ok {
-					runMethod = m
+				context.Output.Header("Allow", strings.Join(sortAllow(allow), ", "))
+				if r.Method == "OPTIONS" {
+					context.Output.SetStatus(http.StatusOK)
 				} else {
-					runMethod = method
+					exception("405", context)
 				}
+				goto After
 			}
-		}
-
-		// also defined runRouter & runMethod from filter
-		if !isRunnable {
-			//Invoke the request handler
-			vc := reflect.New(runRouter)
-			execController, ok := vc.Interface().(ControllerInterface)
-			if !ok {
-				panic("controller is not ControllerInterface")
+		} else if routerInfo.routerType == routerTypegoweb {
+			runRouter = routerInfo.controllerType
+			method := r.Method
+			if r.Method == "POST" && context.Input.Query("_method") == "PUT" {
+				method = "PUT"
 			}
-
-			//call the controller init function
-			execController.Init(context, runRouter.Name(), runMethod, vc.Interface())
-
-			//call prepare function
-			execController.Prepare()
-
-			//if XSRF is Enable then check cookie where there has any cookie in the  request's cookie _csrf
-			if BConfig.WebConfig.EnableXSRF {
-				execController.XSRFToken()
-				if r.Method == "POST" || r.Method == "DELETE" || r.Method == "PUT" ||
-					(r.Method == "POST" && (context.Input.Query("_method") == "DELETE" || context.Input.Query("_method") == "PUT")) {
-					execController.CheckXSRFCookie()
-				}
+			if r.Method == "POST" && context.Input.Query("_method") == "DELETE" {
+				method = "DELETE"
 			}
-
-			execController.URLMapping()
-
-			if !context.ResponseWriter.Started {
-				//exec main logic
-				switch runMethod {
-				case "GET":
-					execController.Get()
-				case "POST":
-					execController.Post()
-				case "DELETE":
-					execController.Delete()
-				case "PUT":
-					execController.Put()
-				case "HEAD":
-					execController.Head()
-				case "PATCH":
-					execController.Patch()
-				case "OPTIONS":
-					execController.Options()
-				default:
-					if !execController.HandlerFunc(runMethod) {
-						var in []reflect.Value
-						method := vc.MethodByName(runMethod)
-						method.Call(in)
-					}
-				}
-
-				//render template
-				if !context.ResponseWriter.Started && context.Output.Status == 0 {
-					if BConfig.WebConfig.AutoRender {
-						if err := execController.Render(); err != nil {
-							panic(err)
-						}
-					}
-				}
+			if m, ok := routerInfo.methods[method]; ok {
+				runMethod = m
+			} else if m, ok = routerInfo.methods["*"]; ok {
+				runMethod = m
+			} else {
+				runMethod = method
 			}
+		}
 
-			// finish all runRouter. release resource
-			execController.Finish()
+		runner := p.routeRunner(routerInfo, runMethod)
+		if routerInfo.timeout != nil {
+			timedOut = runWithTimeout(context, runner, routerInfo.timeout)
+		} else {
+			runner(context)
 		}
+		executed = true
+	}
 
-		//execute middleware filters
-		if p.execFilter(context, AfterExec, urlPath) {
+After:
+	//execute middleware filters, the requests stopped before the router
+	//only run them if AlwaysRunAfterFilters
+	if executed || BConfig.AlwaysRunAfterFilters {
+		// with AlwaysRunAfterFilters, the written response doesn't stop them either
+		always := BConfig.AlwaysRunAfterFilters
+		if table.execFilter(context, AfterExec, urlPath, !always) && !always {
 			goto Admin
 		}
+		table.execFilter(context, FinishRouter, urlPath, !always)
 	}
 
-	p.execFilter(context, FinishRouter, urlPath)
-
 Admin:
 	timeDur := time.Since(startTime)
 	//admin module record QPS
 	if BConfig.Listen.EnableAdmin {
 		if FilterMonitorFunc(r.Method, r.URL.Path, timeDur) {
+			var controllerName string
 			if runRouter != nil {
-				go toolbox.StatisticsMap.AddStatistics(r.Method, r.URL.Path, runRouter.Name(), timeDur)
+				controllerName = runRouter.Name()
+			}
+			if timedOut {
+				go toolbox.StatisticsMap.AddTimeoutStatistics(r.Method, r.URL.Path, controllerName, timeDur)
 			} else {
-				go toolbox.StatisticsMap.AddStatistics(r.Method, r.URL.Path, "", timeDur)
+				go toolbox.StatisticsMap.AddStatistics(r.Method, r.URL.Path, controllerName, timeDur)
 			}
 		}
 	}
@@ -831,17 +1107,247 @@ Admin:
 	}
 
 	// Call WriteHeader if status code has been set changed
-	if context.Output.Status != 0 {
+	if context.Output.Status != 0 && !context.ResponseWriter.Hijacked() {
 		context.ResponseWriter.WriteHeader(context.Output.Status)
 	}
 }
 
+// requestContext derives the context.Context of the request,
+// which is cancelled when the client disconnects or the ServerTimeOut elapses.
+func requestContext(r *http.Request) (gocontext.Context, gocontext.CancelFunc) {
+	if BConfig.Listen.ServerTimeOut > 0 {
+		return gocontext.WithTimeout(r.Context(), time.Duration(BConfig.Listen.ServerTimeOut)*time.Second)
+	}
+	return gocontext.WithCancel(r.Context())
+}
+
+// canonicalPath returns p cleaned, lowercased and with the trailing slash
+// by the policies of BConfig, the ServeHTTP redirects to it if it's not p.
+func canonicalPath(p string) string {
+	if BConfig.RouterCleanPath {
+		cp := path.Clean(p)
+		if strings.HasSuffix(p, "/") && cp != "/" {
+			cp += "/"
+		}
+		p = cp
+	}
+	if BConfig.RouterRedirectCase && !BConfig.RouterCaseSensitive {
+		p = strings.ToLower(p)
+	}
+	if p != "/" {
+		switch BConfig.RouterTrailingSlash {
+		case "strip":
+			if p = strings.TrimRight(p, "/"); p == "" {
+				p = "/"
+			}
+		case "append":
+			if !strings.HasSuffix(p, "/") {
+				p += "/"
+			}
+		}
+	}
+	return p
+}
+
+// redirectPath redirects the request to p with its query,
+// by RouterRedirectCode or the status keeping the request method.
+func redirectPath(context *beecontext.Context, p string) {
+	code := BConfig.RouterRedirectCode
+	if code == 0 {
+		code = http.StatusMovedPermanently
+		if m := context.Request.Method; m != "GET" && m != "HEAD" {
+			code = http.StatusPermanentRedirect
+		}
+	}
+	// a path starting with // would be a protocol-relative url
+	p = "/" + strings.TrimLeft(p, "/")
+	u := (&url.URL{Path: p}).EscapedPath()
+	if q := context.Request.URL.RawQuery; q != "" {
+		u += "?" + q
+	}
+	context.Redirect(code, u)
+}
+
+// matchRouter finds the route of method and urlPath.
+// routers of the matched host patterns are searched before the host-less ones.
+func (t *routerTable) matchRouter(method, urlPath string, context *beecontext.Context) *controllerInfo {
+	for _, h := range t.hostRouters {
+		tr, ok := h.handlers.load().routers[method]
+		if !ok {
+			continue
+		}
+		values, ok := h.match(context)
+		if !ok {
+			continue
+		}
+		if r, ok := tr.Match(urlPath, context).(*controllerInfo); ok {
+			h.setParams(context, values)
+			return r
+		}
+	}
+	if tr, ok := t.routers[method]; ok {
+		if r, ok := tr.Match(urlPath, context).(*controllerInfo); ok {
+			return r
+		}
+	}
+	return nil
+}
+
+// allowMethods returns the sorted http methods whose router tree matches urlPath,
+// OPTIONS is always included as it's answered automatically.
+// it returns nil if no router tree matches urlPath.
+// the params set by the filters are kept.
+func (t *routerTable) allowMethods(urlPath string, context *beecontext.Context) []string {
+	params := context.Input.Params()
+	defer func() {
+		context.Input.ResetParams()
+		for k, v := range params {
+			context.Input.SetParam(k, v)
+		}
+	}()
+	var allow []string
+	for m := range HTTPMETHOD {
+		if m == context.Request.Method {
+			continue
+		}
+		context.Input.ResetParams()
+		if t.matchRouter(m, urlPath, context) != nil {
+			allow = append(allow, m)
+		}
+	}
+	return sortAllow(allow)
+}
+
+// sortAllow sorts the allowed methods with OPTIONS, it returns nil if no method is allowed.
+func sortAllow(allow []string) []string {
+	if len(allow) == 0 {
+		return nil
+	}
+	if !utils.InSlice("OPTIONS", allow) {
+		allow = append(allow, "OPTIONS")
+	}
+	sort.Strings(allow)
+	return allow
+}
+
+// routeRunner returns the FilterFunc which executes the matched route,
+// wrapped by the route's FilterChain in declared order.
+func (p *ControllerRegister) routeRunner(routerInfo *controllerInfo, runMethod string) FilterFunc {
+	runner := func(context *beecontext.Context) {
+		switch routerInfo.routerType {
+		case routerTypeRESTFul:
+			routerInfo.runFunction(context)
+		case routerTypeHandler:
+			routerInfo.handler.ServeHTTP(context.ResponseWriter, context.Request)
+		default:
+			runController(context, routerInfo.controllerType, runMethod, routerInfo.params, routerInfo.pathParams)
+		}
+	}
+	for i := len(routerInfo.chains) - 1; i >= 0; i-- {
+		runner = routerInfo.chains[i](runner)
+	}
+	return runner
+}
+
+// runController invokes the runMethod of a new controller of type runRouter.
+// the arguments of runMethod are bound from the request by params and pathParams,
+// and its results are served by serveResults.
+func runController(context *beecontext.Context, runRouter reflect.Type, runMethod string, params []*methodParam, pathParams []string) {
+	r := context.Request
+	vc := reflect.New(runRouter)
+	execController, ok := vc.Interface().(ControllerInterface)
+	if !ok {
+		panic("controller is not ControllerInterface")
+	}
+
+	//call the controller init function
+	execController.Init(context, runRouter.Name(), runMethod, vc.Interface())
+
+	//call prepare function
+	execController.Prepare()
+
+	//if XSRF is Enable then check cookie where there has any cookie in the  request's cookie _csrf
+	if BConfig.WebConfig.EnableXSRF {
+		execController.XSRFToken()
+		if r.Method == "POST" || r.Method == "DELETE" || r.Method == "PUT" ||
+			(r.Method == "POST" && (context.Input.Query("_method") == "DELETE" || context.Input.Query("_method") == "PUT")) {
+			execController.CheckXSRFCookie()
+		}
+	}
+
+	execController.URLMapping()
+
+	if !context.ResponseWriter.Started {
+		//exec main logic
+		switch runMethod {
+		case "GET":
+			execController.Get()
+		case "POST":
+			execController.Post()
+		case "DELETE":
+			execController.Delete()
+		case "PUT":
+			execController.Put()
+		case "HEAD":
+			execController.Head()
+		case "PATCH":
+			execController.Patch()
+		case "OPTIONS":
+			execController.Options()
+		default:
+			if !execController.HandlerFunc(runMethod) {
+				method := vc.MethodByName(runMethod)
+				in, err := actionArgs(context, method.Type(), params, pathParams)
+				if err != nil {
+					p := errorProblem(http.StatusBadRequest, err)
+					if problemMode(context) {
+						writeProblem(context, p)
+						break
+					}
+					exception(strconv.Itoa(p.Status), context)
+					break
+				}
+				if method.Type().IsVariadic() {
+					serveResults(context, execController, method.CallSlice(in))
+				} else {
+					serveResults(context, execController, method.Call(in))
+				}
+			}
+		}
+
+		//render template
+		if !context.ResponseWriter.Started && context.Output.Status == 0 {
+			if BConfig.WebConfig.AutoRender {
+				if err := execController.Render(); err != nil {
+					panic(err)
+				}
+			}
+		}
+	}
+
+	// finish all runRouter. release resource
+	execController.Finish()
+}
+
 func (p *ControllerRegister) recoverPanic(context *beecontext.Context) {
 	if err := recover(); err != nil {
+		// the panic of a router running with a deadline keeps the stack of its goroutine
+		stack := debug.Stack()
+		if rp, ok := err.(*routerPanic); ok {
+			err, stack = rp.err, rp.stack
+		}
 		if err == ErrAbort {
 			return
 		}
+		// the expected errors are rendered rather than crash
+		if e, ok := err.(error); ok {
+			if ae := appError(e); ae != nil {
+				renderAppError(context, ae)
+				return
+			}
+		}
 		if !BConfig.RecoverPanic {
+			reportPanic(newPanicReport(context, err, stack))
 			panic(err)
 		} else {
 			if BConfig.EnableErrorsShow {
@@ -850,19 +1356,15 @@ func (p *ControllerRegister) recoverPanic(context *beecontext.Context) {
 					return
 				}
 			}
-			var stack string
-			Critical("the request url is ", context.Input.URL())
-			Critical("Handler crashed with error", err)
-			for i := 1; ; i++ {
-				_, file, line, ok := runtime.Caller(i)
-				if !ok {
-					break
+			reportPanic(newPanicReport(context, err, stack))
+			if problemMode(context) {
+				var detail string
+				if BConfig.RunMode == DEV {
+					detail = fmt.Sprint(err)
 				}
-				Critical(fmt.Sprintf("%s:%d", file, line))
-				stack = stack + fmt.Sprintln(fmt.Sprintf("%s:%d", file, line))
-			}
-			if BConfig.RunMode == DEV {
-				showErr(err, context, stack)
+				writeProblem(context, NewProblem(http.StatusInternalServerError, detail))
+			} else if BConfig.RunMode == DEV {
+				showErr(err, context, string(stack))
 			}
 		}
 	}
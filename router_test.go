@@ -15,10 +15,16 @@
 package goweb
 
 import (
+	gocontext "context"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/cooleo/goweb/context"
 )
@@ -142,6 +148,90 @@ func TestUrlFor2(t *testing.T) {
 	}
 }
 
+func TestUrlForName(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/person/:last/:first", &TestController{}, "*:Param")
+	handler.Name("person")
+	handler.Add("/v1/:v/cms_:id(.+)_:page(.+).html", &TestController{}, "*:List")
+	handler.Name("cms")
+	handler.Get("/topic/:id:int/?:page", gowebFilterFunc)
+	handler.Name("topic")
+	handler.Get("/user/:id:uuid", gowebFilterFunc)
+	handler.Name("user.show")
+	AddParamConstraint("interval", func(value string, args []string) bool {
+		_, err := time.ParseDuration(value)
+		return err == nil
+	})
+	handler.Get("/every/:every:interval", gowebFilterFunc)
+	handler.Name("every")
+
+	if a := handler.URLFor("person", ":last", "xie", ":first", "asta"); a != "/person/xie/asta" {
+		t.Errorf("person must equal to /person/xie/asta, but get " + a)
+	}
+	if a := handler.URLFor("cms", ":v", "za", ":id", "12", ":page", "123"); a != "/v1/za/cms_12_123.html" {
+		t.Errorf("cms must equal to /v1/za/cms_12_123.html, but get " + a)
+	}
+	if a := handler.URLFor("topic", ":id", 1, "q", "go"); a != "/topic/1?q=go" {
+		t.Errorf("topic must equal to /topic/1?q=go, but get " + a)
+	}
+	if a := handler.URLFor("topic", ":id", 1, ":page", 2); a != "/topic/1/2" {
+		t.Errorf("topic must equal to /topic/1/2, but get " + a)
+	}
+	if a := handler.URLFor("user.show", ":id", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"); a != "/user/6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+		t.Errorf("user.show must equal to /user/6ba7b810-9dad-11d1-80b4-00c04fd430c8, but get " + a)
+	}
+	if a := handler.URLFor("every", ":every", "1m30s"); a != "/every/1m30s" {
+		t.Errorf("every must equal to /every/1m30s, but get " + a)
+	}
+	if a := handler.URLFor("TestController.Param", ":last", "xie", ":first", "asta"); a != "/person/xie/asta" {
+		t.Errorf("TestController.Param must equal to /person/xie/asta, but get " + a)
+	}
+
+	// missing param and param not matching the constraint
+	for _, test := range []struct {
+		name   string
+		values []interface{}
+	}{
+		{"person", []interface{}{":last", "xie"}},
+		{"user.show", []interface{}{":id", "cooleo"}},
+		{"every", []interface{}{":every", "90"}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("URLFor(%s, %v) should panic in dev mode", test.name, test.values)
+				}
+			}()
+			handler.URLFor(test.name, test.values...)
+		}()
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicated router name should panic in dev mode")
+		}
+	}()
+	handler.Get("/people/:last/:first", gowebFilterFunc)
+	handler.Name("person")
+}
+
+func TestUrlForDuplicatedName(t *testing.T) {
+	BConfig.RunMode = PROD
+	defer func() { BConfig.RunMode = DEV }()
+	handler := NewControllerRegister()
+	handler.Get("/person/:last/:first", gowebFilterFunc)
+	handler.Name("person")
+	handler.Get("/people/:last/:first", gowebFilterFunc)
+	old := handler.last
+	handler.Name("person")
+	if old.name != "" || handler.last.name != "" {
+		t.Error("the duplicated name should not be set on the router")
+	}
+	if a := handler.URLFor("person", ":last", "xie", ":first", "asta"); a != "/person/xie/asta" {
+		t.Errorf("the first router should keep the name, but get " + a)
+	}
+}
+
 func TestUserFunc(t *testing.T) {
 	r, _ := http.NewRequest("GET", "/api/list", nil)
 	w := httptest.NewRecorder()
@@ -214,6 +304,19 @@ func TestAutoExtFunc(t *testing.T) {
 	}
 }
 
+func TestAutoExceptMethod(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.AddAuto(&TestController{})
+	for _, action := range []string{"eventstream", "servejsonarray", "respond", "bindbody", "abortproblem", "aborterror"} {
+		r, _ := http.NewRequest("GET", "/test/"+action, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s shouldn't be an auto router action, got %d", action, w.Code)
+		}
+	}
+}
+
 func TestRouteOk(t *testing.T) {
 
 	r, _ := http.NewRequest("GET", "/person/anderson/thomas?learn=kungfu", nil)
@@ -365,10 +468,620 @@ func TestRouterHandlerAll(t *testing.T) {
 	}
 }
 
+func chainTag(tag string) FilterChain {
+	return func(next FilterFunc) FilterFunc {
+		return func(ctx *context.Context) {
+			ctx.WriteString(tag + ">")
+			next(ctx)
+		}
+	}
+}
+
+func chainHeader(next FilterFunc) FilterFunc {
+	return func(ctx *context.Context) {
+		ctx.Output.Header("X-Chain", "controller")
+		next(ctx)
+	}
+}
+
+func chainStop(next FilterFunc) FilterFunc {
+	return func(ctx *context.Context) {
+		ctx.WriteString("stop")
+	}
+}
+
+func TestRouterChain(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/chain", gowebFilterFunc, chainTag("a"), chainTag("b"))
+	handler.Get("/nochain", gowebFilterFunc)
+	handler.AddWithChain("/chain/controller", &TestController{}, []FilterChain{chainHeader}, "get:List")
+	handler.Handler("/chain/handler", http.HandlerFunc(sayhello), chainTag("h"))
+
+	for url, want := range map[string]string{
+		"/chain":            "a>b>hello",
+		"/nochain":          "hello",
+		"/chain/controller": "i am list",
+		"/chain/handler":    "h>sayhello",
+	} {
+		rw, r := testRequest("GET", url)
+		handler.ServeHTTP(rw, r)
+		if rw.Body.String() != want {
+			t.Errorf("%s: want %q, get %q", url, want, rw.Body.String())
+		}
+	}
+	rw, r := testRequest("GET", "/chain/controller")
+	handler.ServeHTTP(rw, r)
+	if rw.Header().Get("X-Chain") != "controller" {
+		t.Errorf("controller chain did not run")
+	}
+}
+
+func TestRouterChainShortCircuit(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/chain", gowebFilterFunc, chainTag("a"), chainStop, chainTag("b"))
+	rw, r := testRequest("GET", "/chain")
+	handler.ServeHTTP(rw, r)
+	if rw.Body.String() != "a>stop" {
+		t.Errorf("chain should short-circuit the route, get %q", rw.Body.String())
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/user/:id", gowebFilterFunc)
+	handler.Put("/user/:id", gowebFilterFunc)
+
+	rw, r := testRequest("DELETE", "/user/1")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Code set to [%v]; want [%v]", rw.Code, http.StatusMethodNotAllowed)
+	}
+	if allow := rw.Header().Get("Allow"); allow != "GET, OPTIONS, PUT" {
+		t.Errorf("Allow set to [%v]; want [GET, OPTIONS, PUT]", allow)
+	}
+
+	rw, r = testRequest("DELETE", "/order/1")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("Code set to [%v]; want [%v]", rw.Code, http.StatusNotFound)
+	}
+}
+
+func TestMethodNotAllowedKeepsParams(t *testing.T) {
+	BConfig.AlwaysRunAfterFilters = true
+	defer func() { BConfig.AlwaysRunAfterFilters = false }()
+	handler := NewControllerRegister()
+	handler.Get("/user/:id", gowebFilterFunc)
+	var tenant string
+	handler.InsertFilter("*", BeforeRouter, func(ctx *context.Context) {
+		ctx.Input.SetParam("tenant", "goweb")
+	})
+	handler.InsertFilter("*", FinishRouter, func(ctx *context.Context) {
+		tenant = ctx.Input.Param("tenant")
+	}, false)
+
+	rw, r := testRequest("DELETE", "/user/1")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusMethodNotAllowed || tenant != "goweb" {
+		t.Errorf("the filter params should be kept, get %d %q", rw.Code, tenant)
+	}
+}
+
+func TestRESTFulMethodNotAllowed(t *testing.T) {
+	handler := NewControllerRegister()
+	route := &controllerInfo{
+		pattern:     "/only",
+		routerType:  routerTypeRESTFul,
+		runFunction: gowebFilterFunc,
+		methods:     map[string]string{"GET": "GET"},
+	}
+	handler.update(func(t *routerTable) {
+		handler.addToRouter(t, "POST", "/only", route)
+	})
+
+	rw, r := testRequest("POST", "/only")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Code set to [%v]; want [%v]", rw.Code, http.StatusMethodNotAllowed)
+	}
+	if allow := rw.Header().Get("Allow"); allow != "GET, OPTIONS" {
+		t.Errorf("Allow set to [%v]; want [GET, OPTIONS]", allow)
+	}
+}
+
+func TestAutoOptions(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/user/:id", gowebFilterFunc)
+	handler.Post("/user/:id", gowebFilterFunc)
+	handler.Get("/order", gowebFilterFunc)
+	handler.Options("/order", func(ctx *context.Context) {
+		ctx.WriteString("options")
+	})
+
+	rw, r := testRequest("OPTIONS", "/user/1")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusOK {
+		t.Errorf("Code set to [%v]; want [%v]", rw.Code, http.StatusOK)
+	}
+	if allow := rw.Header().Get("Allow"); allow != "GET, OPTIONS, POST" {
+		t.Errorf("Allow set to [%v]; want [GET, OPTIONS, POST]", allow)
+	}
+
+	rw, r = testRequest("OPTIONS", "/order")
+	handler.ServeHTTP(rw, r)
+	if rw.Body.String() != "options" {
+		t.Errorf("explicit OPTIONS handler should run, get %q", rw.Body.String())
+	}
+}
+
 //
 // Benchmarks NewApp:
 //
 
+func TestRoutes(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/user", &TestController{}, "get:List;post:Post")
+	handler.Get("/user/:id", gowebFilterFunc, chainHeader)
+	handler.Handler("/static", http.HandlerFunc(sayhello))
+	handler.InsertFilter("/user/:id", BeforeRouter, gowebBeforeRouter1)
+
+	routes := handler.Routes()
+	var got []string
+	for _, r := range routes {
+		if r.Method == "GET" || r.Method == "POST" {
+			got = append(got, fmt.Sprint(r.Method, " ", r.Pattern, " ", r.Controller, " ", r.Action, " ", len(r.Filters)))
+		}
+	}
+	want := []string{
+		"GET /static  http.HandlerFunc 0",
+		"GET /user goweb.TestController List 0",
+		"GET /user/:id  github.com/cooleo/goweb.gowebFilterFunc 2",
+		"POST /static  http.HandlerFunc 0",
+		"POST /user goweb.TestController Post 0",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Routes got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRouteConflicts(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/user/:id", gowebFilterFunc)
+	handler.Get("/user/:name:string", gowebFilterFunc)
+	handler.Get("/order/:id:int", gowebFilterFunc)
+	handler.Get("/order/:name", gowebFilterFunc)
+	handler.Get("/shop/:id:int", gowebFilterFunc)
+	handler.Get("/shop/:id([a-z]+)", gowebFilterFunc)
+	handler.Get("/shop/new", gowebFilterFunc)
+
+	conflicts := handler.Conflicts()
+	if len(conflicts) != 2 {
+		t.Fatalf("get %d conflicts, want 2: %v", len(conflicts), conflicts)
+	}
+	for _, c := range conflicts {
+		switch c.Pattern {
+		case "/user/:id":
+			if c.Other != "/user/:name:string" || !c.Shadowed {
+				t.Errorf("/user/:name:string should be shadowed, get %v", c)
+			}
+		case "/order/:id:int":
+			if c.Other != "/order/:name" || c.Shadowed {
+				t.Errorf("/order/:name should be ambiguous, get %v", c)
+			}
+		default:
+			t.Errorf("unexpected conflict %v", c)
+		}
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	var reqCtx gocontext.Context
+	handler := NewControllerRegister()
+	handler.Get("/ctx", func(ctx *context.Context) {
+		reqCtx = ctx.Context()
+		if reqCtx.Err() != nil {
+			t.Error("request context should not be done while serving")
+		}
+	})
+
+	rw, r := testRequest("GET", "/ctx")
+	handler.ServeHTTP(rw, r)
+	if reqCtx == nil || reqCtx.Err() != gocontext.Canceled {
+		t.Error("request context should be cancelled when the request is finished")
+	}
+
+	BConfig.Listen.ServerTimeOut = 10
+	defer func() { BConfig.Listen.ServerTimeOut = 0 }()
+	parent, cancel := gocontext.WithCancel(gocontext.Background())
+	handler.Get("/cancel", func(ctx *context.Context) {
+		if _, ok := ctx.Context().Deadline(); !ok {
+			t.Error("request context should have the ServerTimeOut deadline")
+		}
+		cancel()
+		if ctx.Context().Err() == nil {
+			t.Error("request context should be cancelled with the client")
+		}
+	})
+	rw, r = testRequest("GET", "/cancel")
+	handler.ServeHTTP(rw, r.WithContext(parent))
+}
+
+func TestRouteTimeout(t *testing.T) {
+	slow := func(ctx *context.Context) {
+		select {
+		case <-ctx.Context().Done():
+		case <-time.After(time.Second):
+		}
+		ctx.WriteString("slow")
+	}
+	handler := NewControllerRegister()
+	handler.Get("/slow", slow)
+	handler.Timeout(20 * time.Millisecond)
+	handler.Get("/gateway", slow)
+	handler.Timeout(20*time.Millisecond, http.StatusGatewayTimeout)
+	handler.Get("/fast", func(ctx *context.Context) {
+		ctx.Output.Header("X-Fast", "1")
+		ctx.Output.SetStatus(http.StatusCreated)
+		ctx.Output.Body([]byte("fast"))
+	})
+	handler.Timeout(time.Second)
+	release := make(chan struct{})
+	handler.Get("/stubborn", func(ctx *context.Context) {
+		<-release
+		panic("late")
+	})
+	handler.Timeout(20 * time.Millisecond)
+	handler.Get("/crash", func(ctx *context.Context) {
+		panic("boom")
+	})
+	handler.Timeout(time.Second)
+	handler.Get("/stream", func(ctx *context.Context) {
+		ctx.WriteString("first,")
+		ctx.ResponseWriter.Flush()
+		time.Sleep(50 * time.Millisecond)
+		ctx.WriteString("second")
+	})
+	handler.Timeout(20 * time.Millisecond)
+
+	reports := make(chan *PanicReport, 2)
+	reporters := panicReporters
+	panicReporters = nil
+	AddPanicReporter(PanicReporterFunc(func(r *PanicReport) {
+		reports <- r
+	}))
+	defer func() { panicReporters = reporters }()
+
+	rw, r := testRequest("GET", "/stubborn")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("/stubborn should time out with 503, get %d", rw.Code)
+	}
+	close(release)
+	select {
+	case report := <-reports:
+		if report.Error != "late" || !strings.Contains(report.Stack, "TestRouteTimeout.func") {
+			t.Errorf("the late panic should be reported with the stack of the router, get %+v", report)
+		}
+	case <-time.After(time.Second):
+		t.Error("the late panic of /stubborn should be reported")
+	}
+
+	rw, r = testRequest("GET", "/crash")
+	handler.ServeHTTP(rw, r)
+	if report := <-reports; report.Error != "boom" || !strings.Contains(report.Stack, "TestRouteTimeout.func") {
+		t.Errorf("the panic should be reported with the stack of the router, get %+v", report)
+	}
+
+	rw, r = testRequest("GET", "/stream")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusOK || rw.Body.String() != "first,second" || !rw.Flushed {
+		t.Errorf("/stream should be streamed past the deadline, get %d %s", rw.Code, rw.Body.String())
+	}
+
+	rw, r = testRequest("GET", "/slow")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusServiceUnavailable || strings.Contains(rw.Body.String(), "slow") {
+		t.Errorf("/slow should time out with 503, get %d %s", rw.Code, rw.Body.String())
+	}
+
+	rw, r = testRequest("GET", "/gateway")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusGatewayTimeout {
+		t.Errorf("/gateway should time out with 504, get %d", rw.Code)
+	}
+
+	rw, r = testRequest("GET", "/fast")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusCreated || rw.Body.String() != "fast" || rw.Header().Get("X-Fast") != "1" {
+		t.Errorf("/fast should be written out, get %d %s", rw.Code, rw.Body.String())
+	}
+}
+
+type ArgsController struct {
+	Controller
+}
+
+type argsUser struct {
+	ID    int64  `json:"id" form:"id"`
+	Name  string `json:"name" form:"name"`
+	Query string `json:"query"`
+	Token string `json:"token"`
+}
+
+func (a *ArgsController) Show(id int64, q string, token string) (*argsUser, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("user not found")
+	}
+	return &argsUser{ID: id, Query: q, Token: token}, nil
+}
+
+func (a *ArgsController) Create(u *argsUser) *argsUser {
+	return u
+}
+
+func (a *ArgsController) Tags(tags ...string) []string {
+	return tags
+}
+
+type argsUserID int64
+
+type argsSlug string
+
+func (a *ArgsController) Since(id argsUserID, slug argsSlug, since time.Time) string {
+	return fmt.Sprintf("%d %s %s", id, slug, since.Format("2006-01-02"))
+}
+
+func TestActionArgs(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/user/:id:int", &ArgsController{}, "get:Show")
+	handler.Params("id:path", "q:query", "X-Token:header")
+	handler.Add("/user", &ArgsController{}, "post:Create")
+	handler.Add("/tags", &ArgsController{}, "get:Tags")
+	handler.Params("tags:query")
+	handler.Add("/since/:id/:slug/:since", &ArgsController{}, "get:Since")
+
+	rw, r := testRequest("GET", "/since/7/go/2016-01-02")
+	r.Header.Set("Accept", "application/json")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusOK || rw.Body.String() != `"7 go 2016-01-02"` {
+		t.Errorf("Since should bind the named scalars and the time from the path, get %d %s", rw.Code, rw.Body.String())
+	}
+
+	rw, r = testRequest("GET", "/tags?tags[]=a&tags[]=b")
+	r.Header.Set("Accept", "application/json")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), `"b"`) {
+		t.Errorf("Tags should bind the variadic argument, get %d %s", rw.Code, rw.Body.String())
+	}
+
+	rw, r = testRequest("GET", "/user/12?q=cooleo")
+	r.Header.Set("X-Token", "secret")
+	r.Header.Set("Accept", "application/json")
+	handler.ServeHTTP(rw, r)
+	if !strings.Contains(rw.Body.String(), `"id": 12`) || !strings.Contains(rw.Body.String(), `"query": "cooleo"`) ||
+		!strings.Contains(rw.Body.String(), `"token": "secret"`) {
+		t.Errorf("Show should serve the bound user, get %s", rw.Body.String())
+	}
+
+	rw, r = testRequest("GET", "/user/0")
+	r.Header.Set("Accept", "application/xml")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusInternalServerError || rw.Body.String() != "<error>user not found</error>" {
+		t.Errorf("Show should serve the error with 500, get %d %s", rw.Code, rw.Body.String())
+	}
+
+	rw, r = testRequest("POST", "/user")
+	r.Body = ioutil.NopCloser(strings.NewReader(`{"id":3,"name":"cooleo"}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(rw, r)
+	if !strings.Contains(rw.Body.String(), `"name": "cooleo"`) {
+		t.Errorf("Create should decode the json body, get %s", rw.Body.String())
+	}
+
+	rw, r = testRequest("POST", "/user")
+	r.Body = ioutil.NopCloser(strings.NewReader(`{"id":`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusBadRequest {
+		t.Errorf("Create should reject the malformed body with 400, get %d", rw.Code)
+	}
+
+	rw, r = testRequest("POST", "/user")
+	r.Body = ioutil.NopCloser(strings.NewReader("id=4&name=form"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(rw, r)
+	if !strings.Contains(rw.Body.String(), `"name": "form"`) {
+		t.Errorf("Create should parse the form body, get %s", rw.Body.String())
+	}
+}
+
+func TestParamsCopyOnWrite(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/user/:id:int", &ArgsController{}, "get:Show")
+	old := handler.last
+	handler.Params("id:path")
+	if old.params != nil {
+		t.Error("the published router should not be changed")
+	}
+	tr := handler.load().routers["GET"]
+	if handler.last.params == nil || !hasRouter(tr, handler.last) || hasRouter(tr, old) {
+		t.Error("the router should be replaced by the copy with the params")
+	}
+}
+
+func TestRemoveRoute(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/user/:id", func(ctx *context.Context) {
+		ctx.Output.Body([]byte("get"))
+	})
+	handler.Name("user")
+	handler.Post("/user/:id", func(ctx *context.Context) {
+		ctx.Output.Body([]byte("post"))
+	})
+	handler.Get("/user/list", func(ctx *context.Context) {
+		ctx.Output.Body([]byte("list"))
+	})
+
+	if handler.RemoveRoute("get", "/user/:name") {
+		t.Error("RemoveRoute should return false for an unknown pattern")
+	}
+	if !handler.RemoveRoute("get", "/user/:id") {
+		t.Error("RemoveRoute should remove /user/:id")
+	}
+	rw, r := testRequest("GET", "/user/1")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /user/1 should be removed, get %d %s", rw.Code, rw.Body.String())
+	}
+	rw, r = testRequest("POST", "/user/1")
+	handler.ServeHTTP(rw, r)
+	if rw.Body.String() != "post" {
+		t.Errorf("POST /user/1 should be kept, get %s", rw.Body.String())
+	}
+	rw, r = testRequest("GET", "/user/list")
+	handler.ServeHTTP(rw, r)
+	if rw.Body.String() != "list" {
+		t.Errorf("GET /user/list should be kept, get %s", rw.Body.String())
+	}
+	if handler.URLFor("user") != "" {
+		t.Error("the name of the removed router should be released")
+	}
+
+	handler.RemoveRoute("*", "/user/:id")
+	rw, r = testRequest("POST", "/user/1")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("/user/1 should be removed for all methods, get %d", rw.Code)
+	}
+}
+
+func TestAddRouterCopiesPath(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/user/:id", gowebFilterFunc)
+	handler.Get("/order/list", gowebFilterFunc)
+	old := handler.load().routers["GET"]
+	user, order := old.fixrouters[0], old.fixrouters[1]
+	handler.Get("/order/:id", gowebFilterFunc)
+	handler.Name("order")
+
+	tr := handler.load().routers["GET"]
+	if tr == old || tr.fixrouters[0] != user || tr.fixrouters[1] == order {
+		t.Error("only the path of the added router should be copied")
+	}
+	if order.wildcard != nil || !hasRouter(tr.fixrouters[1], handler.last) {
+		t.Error("the published tree should not be changed")
+	}
+	rw, r := testRequest("GET", "/order/1")
+	handler.ServeHTTP(rw, r)
+	if rw.Body.String() != "hello" {
+		t.Errorf("/order/1 should be served, get %s", rw.Body.String())
+	}
+}
+
+func TestRouterHotSwap(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/stable", func(ctx *context.Context) {
+		ctx.Output.Body([]byte("stable"))
+	})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler.Get("/inflight", func(ctx *context.Context) {
+		close(started)
+		<-release
+		ctx.Output.Body([]byte("inflight"))
+	})
+
+	done := make(chan string)
+	go func() {
+		rw, r := testRequest("GET", "/inflight")
+		handler.ServeHTTP(rw, r)
+		done <- rw.Body.String()
+	}()
+	<-started
+	handler.RemoveRoute("get", "/inflight")
+	close(release)
+	if body := <-done; body != "inflight" {
+		t.Errorf("the request in flight should finish on its router, get %s", body)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			pattern := fmt.Sprintf("/plugin/%d", i)
+			handler.Get(pattern, func(ctx *context.Context) {})
+			handler.RemoveRoute("get", pattern)
+		}(i)
+		go func() {
+			defer wg.Done()
+			rw, r := testRequest("GET", "/stable")
+			handler.ServeHTTP(rw, r)
+			if rw.Body.String() != "stable" {
+				t.Errorf("/stable should be served while routers change, get %s", rw.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRouterGroup(t *testing.T) {
+	handler := NewControllerRegister()
+	var trace []string
+	api := handler.Group("/api",
+		GroupBefore(func(ctx *context.Context) {
+			trace = append(trace, "before")
+			if ctx.Input.Query("deny") != "" {
+				ctx.Output.SetStatus(http.StatusForbidden)
+				ctx.Output.Body([]byte("denied"))
+			}
+			if ctx.Input.Query("abort") != "" {
+				ctx.AbortWith(0, "")
+			}
+		}),
+		GroupAfter(func(ctx *context.Context) {
+			trace = append(trace, "after")
+		}),
+	)
+	api.Get("/user", func(ctx *context.Context) {
+		trace = append(trace, "user")
+		ctx.Output.Body([]byte("user"))
+	})
+	api.Group("/v2", GroupBefore(func(ctx *context.Context) {
+		trace = append(trace, "v2")
+	})).Router("/", &TestController{})
+	handler.Get("/apidocs", func(ctx *context.Context) {
+		trace = append(trace, "docs")
+		ctx.Output.Body([]byte("docs"))
+	})
+
+	for _, c := range []struct {
+		url   string
+		body  string
+		trace string
+	}{
+		{"/api/user", "user", "before,user,after"},
+		{"/api/user?deny=1", "denied", "before"},
+		{"/api/user?abort=1", "", "before"},
+		{"/api/v2", "ok", "before,v2,after"},
+		{"/apidocs", "docs", "docs"},
+	} {
+		trace = nil
+		rw, r := testRequest("GET", c.url)
+		handler.ServeHTTP(rw, r)
+		if rw.Body.String() != c.body || strings.Join(trace, ",") != c.trace {
+			t.Errorf("%s should serve %s through %s, get %s through %v", c.url, c.body, c.trace, rw.Body.String(), trace)
+		}
+	}
+
+	trace = nil
+	rw, r := testRequest("GET", "/api/missing")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotFound || len(trace) != 0 {
+		t.Errorf("/api/missing should be 404 without the group filters, get %d through %v", rw.Code, trace)
+	}
+}
+
 func gowebFilterFunc(ctx *context.Context) {
 	ctx.WriteString("hello")
 }
@@ -611,3 +1324,203 @@ func gowebFinishRouter1(ctx *context.Context) {
 func gowebFinishRouter2(ctx *context.Context) {
 	ctx.WriteString("|FinishRouter2")
 }
+
+func TestFilterPriority(t *testing.T) {
+	mux := NewControllerRegister()
+	var trace []string
+	filter := func(name string) FilterFunc {
+		return func(ctx *context.Context) {
+			trace = append(trace, name)
+		}
+	}
+	mux.InsertFilterWithOptions("/*", BeforeRouter, filter("cors"), FilterName("cors"))
+	mux.InsertFilterWithOptions("/*", BeforeRouter, filter("log"), FilterPriority(10))
+	mux.InsertFilterWithOptions("/*", BeforeRouter, filter("auth"), FilterName("auth"), FilterPriority(-10))
+	mux.InsertFilter("/*", BeforeRouter, filter("plain"))
+	mux.Get("/filter", gowebFilterFunc)
+
+	if err := mux.InsertFilterWithOptions("/*", AfterExec, filter("auth"), FilterName("auth")); err == nil {
+		t.Error("a duplicated filter name should be rejected")
+	}
+
+	rw, r := testRequest("GET", "/filter")
+	mux.ServeHTTP(rw, r)
+	if strings.Join(trace, ",") != "auth,cors,plain,log" {
+		t.Errorf("filters should run by priority, get %v", trace)
+	}
+
+	filters := mux.Filters()[BeforeRouter]
+	if len(filters) != 4 || filters[0].Name != "auth" || filters[0].Priority != -10 || filters[0].Pattern != "/*" {
+		t.Errorf("Filters should list the filters in execution order, get %v", filters)
+	}
+
+	if !mux.RemoveFilter("auth") || mux.RemoveFilter("auth") {
+		t.Error("RemoveFilter should remove the auth filter once")
+	}
+	trace = nil
+	rw, r = testRequest("GET", "/filter")
+	mux.ServeHTTP(rw, r)
+	if strings.Join(trace, ",") != "cors,plain,log" {
+		t.Errorf("the removed filter should not run, get %v", trace)
+	}
+}
+
+func TestFilterControl(t *testing.T) {
+	mux := NewControllerRegister()
+	var trace []string
+	mux.InsertFilter("/*", BeforeRouter, func(ctx *context.Context) {
+		trace = append(trace, "auth")
+		switch ctx.Input.Query("action") {
+		case "abort":
+			ctx.AbortWith(http.StatusUnauthorized, "denied")
+		case "skip":
+			ctx.SkipFilters()
+		case "next":
+			ctx.Output.Header("X-Auth", "1")
+			ctx.WriteString("partial ")
+			ctx.Next()
+		}
+	})
+	mux.InsertFilter("/*", BeforeRouter, func(ctx *context.Context) {
+		trace = append(trace, "cors")
+	})
+	mux.InsertFilter("/*", FinishRouter, func(ctx *context.Context) {
+		trace = append(trace, fmt.Sprintf("log:%v", ctx.IsAborted()))
+	}, false)
+	mux.InsertFilter("/*", FinishRouter, func(ctx *context.Context) {
+		trace = append(trace, "metrics")
+	})
+	mux.Get("/control", func(ctx *context.Context) {
+		trace = append(trace, "router")
+		ctx.WriteString("router")
+	})
+
+	for _, c := range []struct {
+		query  string
+		always bool
+		code   int
+		trace  string
+	}{
+		{"", false, http.StatusOK, "auth,cors,router,log:false"},
+		{"action=abort", false, http.StatusUnauthorized, "auth"},
+		{"action=abort", true, http.StatusUnauthorized, "auth,log:true,metrics"},
+		{"action=skip", false, http.StatusOK, "auth,router,log:false"},
+		{"action=next", false, http.StatusOK, "auth,cors,router,log:false"},
+	} {
+		BConfig.AlwaysRunAfterFilters = c.always
+		trace = nil
+		rw, r := testRequest("GET", "/control?"+c.query)
+		mux.ServeHTTP(rw, r)
+		if rw.Code != c.code || strings.Join(trace, ",") != c.trace {
+			t.Errorf("%s should run %s with %d, get %v with %d", c.query, c.trace, c.code, trace, rw.Code)
+		}
+		if c.query == "action=next" && rw.Body.String() != "partial router" {
+			t.Errorf("the router should continue the response, get %s", rw.Body.String())
+		}
+	}
+	BConfig.AlwaysRunAfterFilters = false
+
+	mux.Add("/controller", &TestController{})
+	trace = nil
+	rw, r := testRequest("GET", "/controller?action=next")
+	mux.ServeHTTP(rw, r)
+	if rw.Body.String() != "partial " || strings.Join(trace, ",") != "auth,cors,log:false" {
+		t.Errorf("the controller should not run once the response is written, get %s through %v", rw.Body.String(), trace)
+	}
+}
+
+func TestRedirectPath(t *testing.T) {
+	defer func(cs, rc, cp bool, ts string, code int) {
+		BConfig.RouterCaseSensitive, BConfig.RouterRedirectCase, BConfig.RouterCleanPath = cs, rc, cp
+		BConfig.RouterTrailingSlash, BConfig.RouterRedirectCode = ts, code
+	}(BConfig.RouterCaseSensitive, BConfig.RouterRedirectCase, BConfig.RouterCleanPath,
+		BConfig.RouterTrailingSlash, BConfig.RouterRedirectCode)
+	BConfig.RouterCaseSensitive = false
+	BConfig.RouterRedirectCase = true
+	BConfig.RouterCleanPath = true
+	BConfig.RouterTrailingSlash = "strip"
+
+	handler := NewControllerRegister()
+	handler.Any("/users", func(ctx *context.Context) {
+		ctx.WriteString("users")
+	})
+
+	for _, c := range []struct {
+		method   string
+		url      string
+		code     int
+		location string
+	}{
+		{"GET", "/users", http.StatusOK, ""},
+		{"GET", "/users/?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		{"GET", "//users", http.StatusMovedPermanently, "/users"},
+		{"GET", "/admin/../users", http.StatusMovedPermanently, "/users"},
+		{"GET", "/Users", http.StatusMovedPermanently, "/users"},
+		{"POST", "/users/", http.StatusPermanentRedirect, "/users"},
+	} {
+		rw, r := testRequest(c.method, "http://localhost"+c.url)
+		handler.ServeHTTP(rw, r)
+		if rw.Code != c.code || rw.Header().Get("Location") != c.location {
+			t.Errorf("%s %s should get %d %s, get %d %s", c.method, c.url, c.code, c.location, rw.Code, rw.Header().Get("Location"))
+		}
+	}
+
+	BConfig.RouterCleanPath = false
+	BConfig.RouterTrailingSlash = "append"
+	BConfig.RouterRedirectCode = http.StatusPermanentRedirect
+	rw, r := testRequest("GET", "http://localhost//evil.com")
+	handler.ServeHTTP(rw, r)
+	if rw.Code != http.StatusPermanentRedirect || rw.Header().Get("Location") != "/evil.com/" {
+		t.Errorf("//evil.com should be redirected to a local path, get %d %s", rw.Code, rw.Header().Get("Location"))
+	}
+}
+
+func TestResponseHijack(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/ws", func(ctx *context.Context) {
+		if !ctx.Input.IsWebsocket() {
+			ctx.Output.SetStatus(http.StatusBadRequest)
+			return
+		}
+		ctx.Output.SetStatus(http.StatusSwitchingProtocols)
+		conn, buf, err := ctx.ResponseWriter.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\nhello")
+		buf.Flush()
+	})
+	handler.Handler("/stream", http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if _, ok := rw.(http.Hijacker); !ok {
+			t.Error("the handler should get a http.Hijacker")
+		}
+		rw.Write([]byte("chunk"))
+		rw.(http.Flusher).Flush()
+	}))
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	resp, err := ioutil.ReadAll(conn)
+	if !strings.HasPrefix(string(resp), "HTTP/1.1 101 ") || !strings.HasSuffix(string(resp), "hello") {
+		t.Errorf("the hijacked connection should only get the upgrade response, get %q %v", resp, err)
+	}
+
+	res, err := http.Get(ts.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "chunk" {
+		t.Errorf("/stream should be flushed, get %s", body)
+	}
+}
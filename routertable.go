@@ -0,0 +1,459 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cooleo/goweb/utils"
+)
+
+// routerTable stores the router trees, host routers, names and filters of ControllerRegister.
+// a stored table is never changed: the writers change a copy of it and store the copy,
+// so that the routers can be added and removed while serving,
+// and a request keeps using the table it started with.
+type routerTable struct {
+	routers      map[string]*Tree
+	hostRouters  []*hostRouter
+	names        map[string]*controllerInfo
+	enableFilter bool
+	filters      map[int][]*FilterRouter
+
+	// the tree nodes copied by the writer of the copy, which can be changed
+	owned map[*Tree]bool
+}
+
+func newRouterTable() *routerTable {
+	return &routerTable{
+		routers: make(map[string]*Tree),
+		names:   make(map[string]*controllerInfo),
+		filters: make(map[int][]*FilterRouter),
+	}
+}
+
+// copy returns a copy of the table to change, the tree nodes are copied when they are changed,
+// and the others are shared with t.
+func (t *routerTable) copy() *routerTable {
+	c := &routerTable{
+		routers:      make(map[string]*Tree, len(t.routers)),
+		hostRouters:  t.hostRouters[:len(t.hostRouters):len(t.hostRouters)],
+		names:        make(map[string]*controllerInfo, len(t.names)),
+		enableFilter: t.enableFilter,
+		filters:      make(map[int][]*FilterRouter, len(t.filters)),
+		owned:        make(map[*Tree]bool),
+	}
+	for k, v := range t.routers {
+		c.routers[k] = v
+	}
+	for k, v := range t.names {
+		c.names[k] = v
+	}
+	for k, v := range t.filters {
+		c.filters[k] = v[:len(v):len(v)]
+	}
+	return c
+}
+
+// tree returns the root of the router tree of method which can be changed.
+func (t *routerTable) tree(method string) *Tree {
+	tr, ok := t.routers[method]
+	if !ok {
+		tr = NewTree()
+		t.owned[tr] = true
+	}
+	tr = t.own(tr)
+	t.routers[method] = tr
+	return tr
+}
+
+// own returns the node itself if it's copied by the writer,
+// or else a copy of it sharing the subtrees and leaves.
+func (t *routerTable) own(tr *Tree) *Tree {
+	if t.owned[tr] {
+		return tr
+	}
+	c := &Tree{
+		prefix:     tr.prefix,
+		fixrouters: append([]*Tree(nil), tr.fixrouters...),
+		wildcard:   tr.wildcard,
+		leaves:     append([]*leafInfo(nil), tr.leaves...),
+	}
+	t.owned[c] = true
+	return c
+}
+
+// ownPath copies the nodes under the owned tr which adding segments may change,
+// following the way Tree.addseg and Tree.addtree descend,
+// so that adding a router copies a path of the tree rather than the whole tree.
+func (t *routerTable) ownPath(tr *Tree, segments []string, splat bool) {
+	if len(segments) == 0 {
+		return
+	}
+	seg := segments[0]
+	iswild, params, _ := splitSegment(seg)
+	if len(params) > 0 && params[0] == ":" {
+		t.ownPath(tr, segments[1:], splat)
+	}
+	if iswild || splat {
+		if tr.wildcard != nil {
+			tr.wildcard = t.own(tr.wildcard)
+			t.ownPath(tr.wildcard, segments[1:], splat || utils.InSlice(":splat", params))
+		}
+		return
+	}
+	for i, sub := range tr.fixrouters {
+		if sub.prefix == seg {
+			tr.fixrouters[i] = t.own(sub)
+			t.ownPath(tr.fixrouters[i], segments[1:], splat)
+			return
+		}
+	}
+}
+
+// addRouter adds the router r of pattern to the tree of method.
+func (t *routerTable) addRouter(method, pattern string, r *controllerInfo) {
+	tr := t.tree(method)
+	t.ownPath(tr, splitPath(pattern), false)
+	tr.AddRouter(pattern, r)
+}
+
+// addTree adds the tree to the tree of method with prefix.
+func (t *routerTable) addTree(method, prefix string, tree *Tree) {
+	tr := t.tree(method)
+	t.ownPath(tr, splitPath(prefix), false)
+	tr.AddTree(prefix, tree)
+}
+
+// cloneTree replaces the tree of method by its deep copy, which can be changed as a whole.
+func (t *routerTable) cloneTree(method string) *Tree {
+	tr := t.routers[method].clone()
+	t.routers[method] = tr
+	return tr
+}
+
+// replaceRouter replaces the router old by r in the leaves under the owned tr,
+// only the nodes leading to old are copied.
+func (t *routerTable) replaceRouter(tr *Tree, old, r *controllerInfo) {
+	for i, sub := range tr.fixrouters {
+		if hasRouter(sub, old) {
+			tr.fixrouters[i] = t.own(sub)
+			t.replaceRouter(tr.fixrouters[i], old, r)
+		}
+	}
+	if tr.wildcard != nil && hasRouter(tr.wildcard, old) {
+		tr.wildcard = t.own(tr.wildcard)
+		t.replaceRouter(tr.wildcard, old, r)
+	}
+	for i, l := range tr.leaves {
+		if l.runObject == old {
+			nl := *l
+			nl.runObject = r
+			tr.leaves[i] = &nl
+		}
+	}
+}
+
+// addName names the router r, it returns false if the name is used by another router.
+func (t *routerTable) addName(name string, r *controllerInfo) bool {
+	if old, ok := t.names[name]; ok && old != r {
+		err := fmt.Sprintf("router name %s of %s is already used by %s", name, r.pattern, old.pattern)
+		if BConfig.RunMode == DEV {
+			panic(err)
+		}
+		Warn(err)
+		return false
+	}
+	t.names[name] = r
+	return true
+}
+
+// insertFilterRouter inserts mr after the filters of pos whose priority isn't greater.
+func (t *routerTable) insertFilterRouter(pos int, mr *FilterRouter) {
+	filterList := t.filters[pos]
+	i := len(filterList)
+	for i > 0 && filterList[i-1].priority > mr.priority {
+		i--
+	}
+	newList := make([]*FilterRouter, 0, len(filterList)+1)
+	newList = append(newList, filterList[:i]...)
+	newList = append(newList, mr)
+	t.filters[pos] = append(newList, filterList[i:]...)
+	t.enableFilter = true
+}
+
+// removeFilters removes the filters matched by fn, it returns whether any filter is removed.
+func (t *routerTable) removeFilters(fn func(mr *FilterRouter) bool) bool {
+	var removed bool
+	t.enableFilter = false
+	for pos, filterList := range t.filters {
+		left := make([]*FilterRouter, 0, len(filterList))
+		for _, mr := range filterList {
+			if fn(mr) {
+				removed = true
+			} else {
+				left = append(left, mr)
+			}
+		}
+		if len(left) == 0 {
+			delete(t.filters, pos)
+			continue
+		}
+		t.filters[pos] = left
+		t.enableFilter = true
+	}
+	return removed
+}
+
+// load returns the current router table of ControllerRegister.
+func (p *ControllerRegister) load() *routerTable {
+	return p.table.Load().(*routerTable)
+}
+
+// update changes a copy of the router table with fn and stores it,
+// the writers are serialized.
+func (p *ControllerRegister) update(fn func(t *routerTable)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t := p.load().copy()
+	fn(t)
+	t.owned = nil
+	p.table.Store(t)
+}
+
+// updateLast changes a copy of the last added router with fn in update,
+// and replaces the router by the copy in the table,
+// so that the requests in flight keep using the router they matched.
+// it panics with "there is no router to " + what if no router is added.
+func (p *ControllerRegister) updateLast(what string, fn func(t *routerTable, r *controllerInfo)) {
+	p.update(func(t *routerTable) {
+		old := p.last
+		if old == nil {
+			panic("there is no router to " + what)
+		}
+		c := *old
+		r := &c
+		for m, tr := range t.routers {
+			if hasRouter(tr, old) {
+				t.replaceRouter(t.tree(m), old, r)
+			}
+		}
+		for name, v := range t.names {
+			if v == old {
+				t.names[name] = r
+			}
+		}
+		p.last = r
+		fn(t, r)
+	})
+}
+
+// hasRouter returns whether r is a router of the tree.
+func hasRouter(tr *Tree, r *controllerInfo) bool {
+	var found bool
+	walkTree(tr, func(tr *Tree) {
+		for _, l := range tr.leaves {
+			if l.runObject == r {
+				found = true
+			}
+		}
+	})
+	return found
+}
+
+// clone returns a deep copy of the tree.
+func (t *Tree) clone() *Tree {
+	c := &Tree{prefix: t.prefix}
+	if len(t.fixrouters) > 0 {
+		c.fixrouters = make([]*Tree, len(t.fixrouters))
+		for i, v := range t.fixrouters {
+			c.fixrouters[i] = v.clone()
+		}
+	}
+	if t.wildcard != nil {
+		c.wildcard = t.wildcard.clone()
+	}
+	if len(t.leaves) > 0 {
+		c.leaves = make([]*leafInfo, len(t.leaves))
+		for i, l := range t.leaves {
+			nl := *l
+			nl.wildcards = append([]string(nil), l.wildcards...)
+			if l.constraints != nil {
+				nl.constraints = make(map[string]*paramConstraint, len(l.constraints))
+				for k, v := range l.constraints {
+					nl.constraints[k] = v
+				}
+			}
+			c.leaves[i] = &nl
+		}
+	}
+	return c
+}
+
+// removeLeaves removes the leaves of the routers in rs, or copied from them, from the tree,
+// and the subtrees left empty. it returns whether the tree is empty.
+func (t *Tree) removeLeaves(rs map[*controllerInfo]bool) bool {
+	fixrouters := t.fixrouters[:0]
+	for _, v := range t.fixrouters {
+		if !v.removeLeaves(rs) {
+			fixrouters = append(fixrouters, v)
+		}
+	}
+	t.fixrouters = fixrouters
+	if t.wildcard != nil && t.wildcard.removeLeaves(rs) {
+		t.wildcard = nil
+	}
+	leaves := t.leaves[:0]
+	for _, l := range t.leaves {
+		if r, ok := l.runObject.(*controllerInfo); !ok || !rs[r] && !rs[r.origin] {
+			leaves = append(leaves, l)
+		}
+	}
+	t.leaves = leaves
+	return len(t.fixrouters) == 0 && t.wildcard == nil && len(t.leaves) == 0
+}
+
+// removeRouters removes the routers in rs of the methods from the table,
+// and releases the names of the routers which are not left in any tree.
+func (t *routerTable) removeRouters(methods []string, rs map[*controllerInfo]bool) bool {
+	if len(rs) == 0 {
+		return false
+	}
+	for _, m := range methods {
+		if _, ok := t.routers[m]; !ok {
+			continue
+		}
+		if t.cloneTree(m).removeLeaves(rs) {
+			delete(t.routers, m)
+		}
+	}
+	left := make(map[*controllerInfo]bool)
+	for _, tr := range t.routers {
+		walkTree(tr, func(tr *Tree) {
+			for _, l := range tr.leaves {
+				if r, ok := l.runObject.(*controllerInfo); ok {
+					left[r] = true
+				}
+			}
+		})
+	}
+	for name, r := range t.names {
+		if (rs[r] || rs[r.origin]) && !left[r] {
+			delete(t.names, name)
+		}
+	}
+	return true
+}
+
+// RemoveRoute removes the routers of pattern registered for method,
+// method "*" removes them for all the http methods.
+// the pattern of a Namespace router includes the Namespace prefix.
+// it's safe to call while serving, the requests in flight keep using the removed routers.
+// it returns false if there is no such router.
+// usage:
+//	RemoveRoute("get", "/user/:id")
+//	RemoveRoute("*", "/v1/plugin")
+func (p *ControllerRegister) RemoveRoute(method, pattern string) bool {
+	method = strings.ToUpper(method)
+	var methods []string
+	if method == "*" {
+		for m := range HTTPMETHOD {
+			methods = append(methods, m)
+		}
+	} else {
+		methods = append(methods, method)
+	}
+	var removed bool
+	p.update(func(t *routerTable) {
+		rs := make(map[*controllerInfo]bool)
+		for _, m := range methods {
+			tr, ok := t.routers[m]
+			if !ok {
+				continue
+			}
+			walkTree(tr, func(tr *Tree) {
+				for _, l := range tr.leaves {
+					if r, ok := l.runObject.(*controllerInfo); ok && samePattern(r.pattern, pattern) {
+						rs[r] = true
+					}
+				}
+			})
+		}
+		removed = t.removeRouters(methods, rs)
+	})
+	return removed
+}
+
+func samePattern(a, b string) bool {
+	if BConfig.RouterCaseSensitive {
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
+
+// removeTable removes the routers of nt from handlers.
+func removeTable(handlers *ControllerRegister, nt *routerTable) {
+	rs := make(map[*controllerInfo]bool)
+	methods := make([]string, 0, len(nt.routers))
+	for m, tr := range nt.routers {
+		methods = append(methods, m)
+		walkTree(tr, func(tr *Tree) {
+			for _, l := range tr.leaves {
+				if r, ok := l.runObject.(*controllerInfo); ok {
+					rs[r] = true
+				}
+			}
+		})
+	}
+	handlers.update(func(t *routerTable) {
+		t.removeRouters(methods, rs)
+	})
+}
+
+// RemoveNamespace removes the routers, names and filters added by AddNamespace,
+// so that a Namespace can be mounted and unmounted while serving.
+// the requests in flight keep using the removed routers.
+// usage:
+//	ns := goweb.NewNamespace("/plugin", ...)
+//	goweb.AddNamespace(ns)
+//	goweb.RemoveNamespace(ns)
+func RemoveNamespace(nl ...*Namespace) {
+	for _, n := range nl {
+		handlers := BeeApp.Handlers
+		if n.host != "" {
+			handlers = BeeApp.Handlers.Host(n.host)
+		}
+		nt := n.handlers.load()
+		removeTable(handlers, nt)
+		for _, h := range nt.hostRouters {
+			removeTable(BeeApp.Handlers.Host(h.pattern), h.handlers.load())
+		}
+
+		fs := make(map[*FilterRouter]bool)
+		for _, filterList := range nt.filters {
+			for _, mr := range filterList {
+				fs[mr] = true
+			}
+		}
+		if len(fs) == 0 {
+			continue
+		}
+		BeeApp.Handlers.update(func(t *routerTable) {
+			t.removeFilters(func(mr *FilterRouter) bool {
+				return fs[mr.origin]
+			})
+		})
+	}
+}
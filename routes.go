@@ -0,0 +1,250 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	beecontext "github.com/cooleo/goweb/context"
+	"github.com/cooleo/goweb/utils"
+)
+
+// RouteInfo describes a registered router rule.
+type RouteInfo struct {
+	Method  string
+	Pattern string
+	// Host is the host pattern the router is bound to, empty for any host.
+	Host string
+	// Controller is the controller type, empty for func and handler routers.
+	Controller string
+	// Action is the controller method, or the name of the func or handler.
+	Action string
+	// Filters are the FilterChain of the router and the inserted filters matching its pattern.
+	Filters []string
+	// Name is the name of the router set by Name.
+	Name string
+}
+
+// RouteConflict describes two router rules of the same method
+// which can match the same request.
+type RouteConflict struct {
+	Method string
+	Host   string
+	// Pattern is matched first, so it wins the requests of Other.
+	Pattern string
+	Other   string
+	// Shadowed is true when Other can never be matched.
+	Shadowed bool
+}
+
+func (c RouteConflict) String() string {
+	method := c.Method
+	if c.Host != "" {
+		method += " " + c.Host
+	}
+	if c.Shadowed {
+		return fmt.Sprintf("router %s %s is shadowed by %s", method, c.Other, c.Pattern)
+	}
+	return fmt.Sprintf("router %s %s is ambiguous with %s, %s is matched first", method, c.Other, c.Pattern, c.Pattern)
+}
+
+// Routes returns all the router rules of ControllerRegister,
+// sorted by host, method and pattern.
+func (p *ControllerRegister) Routes() []RouteInfo {
+	var routes []RouteInfo
+	p.walkTrees(func(method string, t *Tree) {
+		for _, l := range t.leaves {
+			if r, ok := l.runObject.(*controllerInfo); ok {
+				routes = append(routes, p.routeInfo(method, r))
+			}
+		}
+	})
+	for _, h := range p.load().hostRouters {
+		for _, route := range h.handlers.Routes() {
+			route.Host = h.pattern
+			routes = append(routes, route)
+		}
+	}
+	sort.Stable(routeInfos(routes))
+	return routes
+}
+
+func (p *ControllerRegister) routeInfo(method string, r *controllerInfo) RouteInfo {
+	route := RouteInfo{Method: method, Pattern: r.pattern, Name: r.name}
+	switch r.routerType {
+	case routerTypegoweb:
+		route.Controller = r.controllerType.String()
+		if m, ok := r.methods[method]; ok {
+			route.Action = m
+		} else if m, ok = r.methods["*"]; ok {
+			route.Action = m
+		} else {
+			route.Action = strings.Title(strings.ToLower(method))
+		}
+	case routerTypeRESTFul:
+		route.Action = utils.GetFuncName(r.runFunction)
+	case routerTypeHandler:
+		route.Action = fmt.Sprintf("%T", r.handler)
+	}
+	for _, c := range r.chains {
+		route.Filters = append(route.Filters, utils.GetFuncName(c))
+	}
+	if table := p.load(); table.enableFilter {
+		ctx := beecontext.NewContext()
+		for pos := BeforeStatic; pos <= FinishRouter; pos++ {
+			for _, f := range table.filters[pos] {
+				ctx.Input.ResetParams()
+				if f.ValidRouter(r.pattern, ctx) {
+					route.Filters = append(route.Filters, f.info().Name)
+				}
+			}
+		}
+	}
+	return route
+}
+
+// Conflicts returns the router rules which are ambiguous with, or shadowed by,
+// a rule registered before them under the same method.
+// A rule is shadowed when the former one has the same wildcards without any
+// regexp or constraint, so that it matches every request of the later one.
+func (p *ControllerRegister) Conflicts() []RouteConflict {
+	var conflicts []RouteConflict
+	p.walkTrees(func(method string, t *Tree) {
+		for i, a := range t.leaves {
+			ra, ok := a.runObject.(*controllerInfo)
+			if !ok {
+				continue
+			}
+			for _, b := range t.leaves[i+1:] {
+				rb, ok := b.runObject.(*controllerInfo)
+				if !ok {
+					continue
+				}
+				if shadowed, ok := leafConflict(a, b); ok {
+					conflicts = append(conflicts, RouteConflict{
+						Method:   method,
+						Pattern:  ra.pattern,
+						Other:    rb.pattern,
+						Shadowed: shadowed,
+					})
+				}
+			}
+		}
+	})
+	for _, h := range p.load().hostRouters {
+		for _, c := range h.handlers.Conflicts() {
+			c.Host = h.pattern
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts
+}
+
+// leafConflict checks whether the leaf a matches the requests of b,
+// which is added after a to the same tree.
+func leafConflict(a, b *leafInfo) (shadowed bool, ok bool) {
+	if len(a.wildcards) != len(b.wildcards) || leafKind(a) != leafKind(b) {
+		return false, false
+	}
+	if a.regexps == nil && len(a.constraints) == 0 {
+		return true, true
+	}
+	if a.regexps != nil && b.regexps != nil {
+		if a.regexps.String() != b.regexps.String() {
+			return false, false
+		}
+		return len(a.constraints) == 0, true
+	}
+	return false, true
+}
+
+func leafKind(l *leafInfo) string {
+	for _, w := range l.wildcards {
+		if w == ":splat" || w == ":path" {
+			return w
+		}
+	}
+	return ":"
+}
+
+// checkRoutes reports the conflicts of the routers at the start of application in dev mode.
+// the conflicts of a router registered for several methods are reported once.
+func checkRoutes() error {
+	if BConfig.RunMode == DEV {
+		var (
+			conflicts []RouteConflict
+			index     = make(map[RouteConflict]int)
+		)
+		for _, c := range BeeApp.Handlers.Conflicts() {
+			method := c.Method
+			c.Method = ""
+			if i, ok := index[c]; ok {
+				conflicts[i].Method += "," + method
+				continue
+			}
+			index[c] = len(conflicts)
+			c.Method = method
+			conflicts = append(conflicts, c)
+		}
+		for _, c := range conflicts {
+			if c.Shadowed {
+				Error(c.String())
+			} else {
+				Warn(c.String())
+			}
+		}
+	}
+	return nil
+}
+
+func (p *ControllerRegister) walkTrees(fn func(method string, t *Tree)) {
+	routers := p.load().routers
+	methods := make([]string, 0, len(routers))
+	for method := range routers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		walkTree(routers[method], func(t *Tree) {
+			fn(method, t)
+		})
+	}
+}
+
+func walkTree(t *Tree, fn func(t *Tree)) {
+	fn(t)
+	for _, tr := range t.fixrouters {
+		walkTree(tr, fn)
+	}
+	if t.wildcard != nil {
+		walkTree(t.wildcard, fn)
+	}
+}
+
+type routeInfos []RouteInfo
+
+func (r routeInfos) Len() int      { return len(r) }
+func (r routeInfos) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r routeInfos) Less(i, j int) bool {
+	if r[i].Host != r[j].Host {
+		return r[i].Host < r[j].Host
+	}
+	if r[i].Method != r[j].Method {
+		return r[i].Method < r[j].Method
+	}
+	return r[i].Pattern < r[j].Pattern
+}
@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"sync"
@@ -34,11 +35,93 @@ var (
 	// beeTemplates caching map and supported template file extensions.
 	beeTemplates  = make(map[string]*template.Template)
 	templatesLock sync.RWMutex
+	// templateDeps maps the templates to the files parsed into them by getTplDeep,
+	// the templates are rebuilt when any of the files changes.
+	templateDeps = make(map[string][]string)
+	// templateErrors stores the errors of the templates failing to build.
+	templateErrors = make(map[string]error)
 	// beeTemplateExt stores the template extension which will build
 	beeTemplateExt = []string{"tpl", "html"}
 )
 
+// TemplateEngine loads and renders the template files of its extensions,
+// the templates are named by their paths relative to the views path, eg. "user/show.tpl".
+// Controller.Render runs the template, layout and LayoutSections with their engines,
+// so that a layout of an engine can contain the templates of the others.
+type TemplateEngine interface {
+	// Load parses the files under root at start.
+	Load(root string, files []string) error
+	// Reload reparses the files under root which may be changed, in the dev mode.
+	Reload(root string, files []string) error
+	// Render executes the template name with data.
+	Render(wr io.Writer, name string, data interface{}) error
+	// Funcs adds the functions which can be called in the templates.
+	Funcs(funcs map[string]interface{})
+}
+
+var (
+	defaultTemplateEngine TemplateEngine = htmlTemplateEngine{}
+	// templateEngines maps the template file extensions to their engines.
+	templateEngines = map[string]TemplateEngine{"tpl": defaultTemplateEngine, "html": defaultTemplateEngine}
+)
+
+// templateEngine returns the engine of the template file by its extension.
+func templateEngine(file string) TemplateEngine {
+	if ext := templateExt(file); ext != "" {
+		return templateEngines[ext]
+	}
+	return nil
+}
+
+// templateExt returns the template extension of file, or "" if it isn't a template file.
+func templateExt(file string) string {
+	for _, v := range beeTemplateExt {
+		if strings.HasSuffix(file, "."+v) {
+			return v
+		}
+	}
+	return ""
+}
+
 func executeTemplate(wr io.Writer, name string, data interface{}) error {
+	if e := templateEngine(name); e != nil {
+		return e.Render(wr, name, data)
+	}
+	panic("can't find templatefile in the path:" + name)
+}
+
+// htmlTemplateEngine is the default TemplateEngine of html/template,
+// a template can include the templates in its directory.
+type htmlTemplateEngine struct{}
+
+func (htmlTemplateEngine) Load(root string, files []string) error {
+	parseTemplates(root, files, nil)
+	return nil
+}
+
+func (htmlTemplateEngine) Reload(root string, files []string) error {
+	self := &templateFile{
+		root:  root,
+		files: make(map[string][]string),
+	}
+	err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if f != nil && !f.IsDir() && templateEngine(path) != defaultTemplateEngine {
+			return nil
+		}
+		return self.visit(path, f, err)
+	})
+	if err != nil {
+		return err
+	}
+	var all []string
+	for _, v := range self.files {
+		all = append(all, v...)
+	}
+	parseTemplates(root, all, files)
+	return nil
+}
+
+func (htmlTemplateEngine) Render(wr io.Writer, name string, data interface{}) error {
 	if BConfig.RunMode == DEV {
 		templatesLock.RLock()
 		defer templatesLock.RUnlock()
@@ -53,6 +136,51 @@ func executeTemplate(wr io.Writer, name string, data interface{}) error {
 	panic("can't find templatefile in the path:" + name)
 }
 
+// Funcs adds the functions to gowebTplFuncMap, which the templates parsed later use.
+func (htmlTemplateEngine) Funcs(funcs map[string]interface{}) {
+	for k, v := range funcs {
+		gowebTplFuncMap[k] = v
+	}
+}
+
+// parseTemplates parses the files in only, or all the files if only is empty,
+// with the files of their directories in all.
+func parseTemplates(root string, all, only []string) {
+	dirs := make(map[string][]string)
+	for _, file := range all {
+		subDir := filepath.Dir(file)
+		dirs[subDir] = append(dirs[subDir], file)
+	}
+	for _, v := range dirs {
+		for _, file := range v {
+			if len(only) == 0 || utils.InSlice(file, only) {
+				templatesLock.Lock()
+				t, err := getTemplate(root, file, v...)
+				if err != nil {
+					Trace("parse template err:", file, err)
+					templateErrors[file] = err
+				} else {
+					beeTemplates[file] = t
+					templateDeps[file] = templateFiles(t)
+					delete(templateErrors, file)
+				}
+				templatesLock.Unlock()
+			}
+		}
+	}
+}
+
+// templateFiles returns the template files parsed into t.
+func templateFiles(t *template.Template) []string {
+	var files []string
+	for _, tl := range t.Templates() {
+		if HasTemplateExt(tl.Name()) {
+			files = append(files, tl.Name())
+		}
+	}
+	return files
+}
+
 func init() {
 	gowebTplFuncMap["dateformat"] = DateFormat
 	gowebTplFuncMap["date"] = Date
@@ -83,11 +211,51 @@ func init() {
 }
 
 // AddFuncMap let user to register a func in the template.
+// the func is added to all the template engines.
 func AddFuncMap(key string, fn interface{}) error {
 	gowebTplFuncMap[key] = fn
+	for _, e := range uniqueTemplateEngines() {
+		if e.engine != defaultTemplateEngine {
+			e.engine.Funcs(map[string]interface{}{key: fn})
+		}
+	}
 	return nil
 }
 
+// engineExts is a template engine with its extensions.
+type engineExts struct {
+	engine TemplateEngine
+	exts   []string
+}
+
+// uniqueTemplateEngines returns the engines of the template extensions, each once.
+func uniqueTemplateEngines() []engineExts {
+	var engines []engineExts
+	for _, v := range beeTemplateExt {
+		e := templateEngines[v]
+		seen := false
+		for i := range engines {
+			if sameTemplateEngine(engines[i].engine, e) {
+				engines[i].exts = append(engines[i].exts, v)
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			engines = append(engines, engineExts{engine: e, exts: []string{v}})
+		}
+	}
+	return engines
+}
+
+// sameTemplateEngine returns whether a and b are the same engine,
+// the engines of an uncomparable type, eg. a struct holding a map, are compared as different,
+// so that they don't panic as the map keys or with ==.
+func sameTemplateEngine(a, b TemplateEngine) bool {
+	t := reflect.TypeOf(a)
+	return t != nil && t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
 type templateFile struct {
 	root  string
 	files map[string][]string
@@ -127,7 +295,17 @@ func HasTemplateExt(paths string) bool {
 }
 
 // AddTemplateExt add new extension for template.
-func AddTemplateExt(ext string) {
+// the templates of ext are rendered by engine, or by html/template if it's omitted,
+// the engine gets the funcs added by AddFuncMap.
+// usage:
+//	goweb.AddTemplateExt("jet", jetEngine)
+func AddTemplateExt(ext string, engine ...TemplateEngine) {
+	e := defaultTemplateEngine
+	if len(engine) > 0 && engine[0] != nil {
+		e = engine[0]
+		e.Funcs(gowebTplFuncMap)
+	}
+	templateEngines[ext] = e
 	for _, v := range beeTemplateExt {
 		if v == ext {
 			return
@@ -138,6 +316,8 @@ func AddTemplateExt(ext string) {
 
 // BuildTemplate will build all template files in a directory.
 // it makes goweb can render any template file in view directory.
+// the files are loaded by the engines of their extensions,
+// or reloaded if files are given.
 func BuildTemplate(dir string, files ...string) error {
 	if _, err := os.Stat(dir); err != nil {
 		if os.IsNotExist(err) {
@@ -156,21 +336,46 @@ func BuildTemplate(dir string, files ...string) error {
 		fmt.Printf("filepath.Walk() returned %v\n", err)
 		return err
 	}
-	for _, v := range self.files {
-		for _, file := range v {
-			if len(files) == 0 || utils.InSlice(file, files) {
-				templatesLock.Lock()
-				t, err := getTemplate(self.root, file, v...)
+	var loadErr error
+	for _, ee := range uniqueTemplateEngines() {
+		e := ee.engine
+		// the files of the engine
+		var engineFiles []string
+		for _, v := range self.files {
+			for _, file := range v {
+				if (len(files) == 0 || utils.InSlice(file, files)) && utils.InSlice(templateExt(file), ee.exts) {
+					engineFiles = append(engineFiles, file)
+				}
+			}
+		}
+		if len(engineFiles) == 0 {
+			continue
+		}
+		if len(files) == 0 {
+			err = e.Load(dir, engineFiles)
+		} else {
+			err = e.Reload(dir, engineFiles)
+		}
+		// parseTemplates keeps the errors of the default engine by file
+		if e != defaultTemplateEngine {
+			templatesLock.Lock()
+			for _, file := range engineFiles {
 				if err != nil {
-					Trace("parse template err:", file, err)
+					templateErrors[file] = err
 				} else {
-					beeTemplates[file] = t
+					delete(templateErrors, file)
 				}
-				templatesLock.Unlock()
+			}
+			templatesLock.Unlock()
+		}
+		if err != nil {
+			Trace("load template err:", err)
+			if loadErr == nil {
+				loadErr = err
 			}
 		}
 	}
-	return nil
+	return loadErr
 }
 
 func getTplDeep(root, file, parent string, t *template.Template) (*template.Template, [][]string, error) {
@@ -15,9 +15,19 @@
 package goweb
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/cooleo/goweb/context"
+	"github.com/cooleo/goweb/utils"
 )
 
 var header = `{{define "header"}}
@@ -134,3 +144,253 @@ func TestRelativeTemplate(t *testing.T) {
 	}
 	os.RemoveAll(dir)
 }
+
+// fakeEngine renders the content of a template file with the funcs applied to data.
+type fakeEngine struct {
+	templates map[string]string
+	funcs     map[string]interface{}
+	reloaded  []string
+}
+
+func (e *fakeEngine) Load(root string, files []string) error {
+	e.templates = make(map[string]string)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			return err
+		}
+		e.templates[file] = string(content)
+	}
+	return nil
+}
+
+func (e *fakeEngine) Reload(root string, files []string) error {
+	e.reloaded = append(e.reloaded, files...)
+	return nil
+}
+
+func (e *fakeEngine) Render(wr io.Writer, name string, data interface{}) error {
+	t, ok := e.templates[name]
+	if !ok {
+		return fmt.Errorf("fake: no template %s", name)
+	}
+	upper := e.funcs["upper"].(func(string) string)
+	_, err := io.WriteString(wr, strings.Replace(t, "$name", upper(fmt.Sprint(data.(map[interface{}]interface{})["Name"])), -1))
+	return err
+}
+
+func (e *fakeEngine) Funcs(funcs map[string]interface{}) {
+	if e.funcs == nil {
+		e.funcs = make(map[string]interface{})
+	}
+	for k, v := range funcs {
+		e.funcs[k] = v
+	}
+}
+
+func TestTemplateEngine(t *testing.T) {
+	dir := "_beeEngineTmp"
+	files := map[string]string{
+		"layout.tpl":   `<body>{{.LayoutContent}}</body>`,
+		"user/show.fk": `<h1>$name</h1>`,
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0777)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer os.RemoveAll(dir)
+
+	e := &fakeEngine{}
+	AddTemplateExt("fk", e)
+	AddFuncMap("upper", strings.ToUpper)
+	if err := BuildTemplate(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := beeTemplates["user/show.fk"]; ok {
+		t.Error("the fk template should not be parsed by html/template")
+	}
+
+	viewPath := BConfig.WebConfig.ViewsPath
+	BConfig.WebConfig.ViewsPath = dir
+	defer func() { BConfig.WebConfig.ViewsPath = viewPath }()
+	c := &Controller{Data: map[interface{}]interface{}{"Name": "cooleo"}}
+	c.TplName = "user/show.fk"
+	c.Layout = "layout.tpl"
+	out, err := c.RenderBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, []byte("<body><h1>COOLEO</h1></body>")) {
+		t.Errorf("unexpected render: %s", out)
+	}
+
+	e.reloaded = nil
+	if err := BuildTemplate(dir, "user/show.fk", "layout.tpl"); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.reloaded) != 1 || e.reloaded[0] != "user/show.fk" {
+		t.Errorf("the changed file should be reloaded, got %v", e.reloaded)
+	}
+}
+
+func TestRenderRebuildsTemplates(t *testing.T) {
+	dir := "_beeRenderTmp"
+	os.MkdirAll(dir, 0777)
+	defer os.RemoveAll(dir)
+	defer func(mode, viewPath string) {
+		BConfig.RunMode, BConfig.WebConfig.ViewsPath = mode, viewPath
+	}(BConfig.RunMode, BConfig.WebConfig.ViewsPath)
+	BConfig.RunMode, BConfig.WebConfig.ViewsPath = DEV, dir
+
+	for _, v := range []string{"v1", "v2"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "page.tpl"), []byte(v), 0666); err != nil {
+			t.Fatal(err)
+		}
+		c := &Controller{Data: map[interface{}]interface{}{}, TplName: "page.tpl"}
+		if out, err := c.RenderString(); err != nil || out != v {
+			t.Errorf("the changed template should be rebuilt without the watcher, got %s, %v", out, err)
+		}
+	}
+}
+
+// failEngine is an engine of an uncomparable type failing to load.
+type failEngine map[string]string
+
+func (failEngine) Load(root string, files []string) error {
+	return fmt.Errorf("fail: can't load %v", files)
+}
+
+func (failEngine) Reload(root string, files []string) error { return nil }
+
+func (failEngine) Render(wr io.Writer, name string, data interface{}) error { return nil }
+
+func (failEngine) Funcs(funcs map[string]interface{}) {}
+
+func TestTemplateEngineError(t *testing.T) {
+	dir := "_beeEngineErrTmp"
+	os.MkdirAll(dir, 0777)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.fail", "b.fail2"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(exts []string) {
+		beeTemplateExt = exts
+		delete(templateEngines, "fail")
+		delete(templateEngines, "fail2")
+		delete(templateErrors, "a.fail")
+		delete(templateErrors, "b.fail2")
+	}(beeTemplateExt)
+	e := failEngine{}
+	AddTemplateExt("fail", e)
+	AddTemplateExt("fail2", e)
+	AddFuncMap("fail", strings.ToUpper)
+	if err := BuildTemplate(dir); err == nil || !strings.Contains(err.Error(), "a.fail") {
+		t.Errorf("the load error should be returned, got %v", err)
+	}
+}
+
+func TestTemplateWatcher(t *testing.T) {
+	dir := "_beeWatchTmp"
+	write := func(name, content string, mtime time.Time) {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0777)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(filepath.Join(dir, name), mtime, mtime)
+	}
+	render := func(name string) string {
+		var buf bytes.Buffer
+		if err := executeTemplate(&buf, name, nil); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	now := time.Now()
+	write("watch/page.tpl", `<body>{{template "watch/inc.tpl"}}</body>`, now)
+	write("watch/inc.tpl", `v1`, now)
+	write("watch/other.tpl", `other`, now)
+	defer os.RemoveAll(dir)
+	if err := BuildTemplate(dir); err != nil {
+		t.Fatal(err)
+	}
+	w := newTemplateWatcher(dir)
+	ch := w.subscribe()
+	defer w.unsubscribe(ch)
+	if w.check() {
+		t.Error("nothing should change")
+	}
+
+	deps := templateDependents([]string{"watch/inc.tpl"})
+	if !utils.InSlice("watch/page.tpl", deps) || utils.InSlice("watch/other.tpl", deps) {
+		t.Errorf("only the templates including watch/inc.tpl should be rebuilt, got %v", deps)
+	}
+
+	write("watch/inc.tpl", `v2`, now.Add(time.Second))
+	if !w.check() {
+		t.Fatal("watch/inc.tpl should change")
+	}
+	if out := render("watch/page.tpl"); out != "<body>v2</body>" {
+		t.Errorf("the dependent template should be rebuilt, got %s", out)
+	}
+	if e := <-ch; e.Event != "reload" {
+		t.Errorf("should notify reload, got %s", e.Event)
+	}
+
+	write("watch/inc.tpl", `{{if}}`, now.Add(2*time.Second))
+	w.check()
+	if _, ok := buildErrors()["watch/inc.tpl"]; !ok {
+		t.Error("the parse error should be kept")
+	}
+	if e := <-ch; e.Event != "templateerror" {
+		t.Errorf("should notify templateerror, got %s", e.Event)
+	}
+	page := string(injectLiveReload([]byte("<html><body><p>hi</p></body></html>")))
+	if !strings.Contains(page, `"watch/inc.tpl":`) || !strings.HasSuffix(page, "</script></body></html>") {
+		t.Errorf("the live-reload script should be injected with the errors, got %s", page)
+	}
+	if page := string(injectLiveReload([]byte("<p>hi</p>"))); page != "<p>hi</p>" {
+		t.Errorf("the page without </body> should be unchanged, got %s", page)
+	}
+
+	defer func(mode, viewPath string, liveReload bool, watch *templateWatcher) {
+		BConfig.RunMode, BConfig.WebConfig.ViewsPath, BConfig.WebConfig.EnableLiveReload = mode, viewPath, liveReload
+		templateWatch = watch
+	}(BConfig.RunMode, BConfig.WebConfig.ViewsPath, BConfig.WebConfig.EnableLiveReload, templateWatch)
+	BConfig.RunMode, BConfig.WebConfig.ViewsPath, BConfig.WebConfig.EnableLiveReload = DEV, dir, true
+	templateWatch = w
+	rw, r := testRequest("GET", "/watch")
+	ctx := context.NewContext()
+	ctx.Reset(rw, r)
+	c := &Controller{Ctx: ctx, Data: map[interface{}]interface{}{}, TplName: "watch/page.tpl", EnableRender: true}
+	if err := c.Render(); err != nil {
+		t.Fatal(err)
+	}
+	if rw.Code != http.StatusInternalServerError || !strings.Contains(rw.Body.String(), `"watch/page.tpl":`) {
+		t.Errorf("the failing page should show the overlay, got %d %s", rw.Code, rw.Body.String())
+	}
+
+	write("watch/inc.tpl", `v3`, now.Add(3*time.Second))
+	w.check()
+	if len(buildErrors()) != 0 {
+		t.Errorf("the errors should be cleared, got %v", buildErrors())
+	}
+	if out := render("watch/page.tpl"); out != "<body>v3</body>" {
+		t.Errorf("the template should be rebuilt, got %s", out)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.watch(time.Millisecond)
+		close(done)
+	}()
+	w.close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("the closed watcher should stop watching")
+	}
+}
@@ -241,6 +241,7 @@ func Htmlunquote(src string) string {
 }
 
 // URLFor returns url string with another registered controller handler with params.
+// endpoint is resolved as a router name first, refer: ControllerRegister.Name
 //	usage:
 //
 //	URLFor(".index")
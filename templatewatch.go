@@ -0,0 +1,276 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cooleo/goweb/context"
+	"github.com/cooleo/goweb/utils"
+)
+
+// TemplateWatchInterval is the interval of polling the views path for the changed templates in the dev mode.
+var TemplateWatchInterval = time.Second
+
+// liveReloadPath is the path of the event stream reloading the dev pages.
+const liveReloadPath = "/_goweb/livereload"
+
+// templateWatcher rebuilds the changed templates of the views path and their dependents,
+// and notifies the live-reload clients.
+type templateWatcher struct {
+	root    string
+	mtimes  map[string]time.Time
+	lock    sync.Mutex
+	clients map[chan *context.Event]bool
+	stop    chan struct{}
+}
+
+var templateWatch *templateWatcher
+
+func newTemplateWatcher(root string) *templateWatcher {
+	w := &templateWatcher{root: root, clients: make(map[chan *context.Event]bool), stop: make(chan struct{})}
+	w.mtimes, _ = w.scan()
+	return w
+}
+
+// scan returns the modification times of the template files.
+func (w *templateWatcher) scan() (map[string]time.Time, error) {
+	tf := &templateFile{
+		root:  w.root,
+		files: make(map[string][]string),
+	}
+	if err := filepath.Walk(w.root, tf.visit); err != nil {
+		return nil, err
+	}
+	mtimes := make(map[string]time.Time)
+	for _, v := range tf.files {
+		for _, file := range v {
+			if f, err := os.Stat(filepath.Join(w.root, file)); err == nil {
+				mtimes[file] = f.ModTime()
+			}
+		}
+	}
+	return mtimes, nil
+}
+
+// watch checks the templates every interval until the watcher is closed.
+func (w *templateWatcher) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// close stops watching the templates.
+func (w *templateWatcher) close() {
+	close(w.stop)
+}
+
+// check rebuilds the changed templates, and returns whether any template changed.
+// all the templates are rebuilt if a file is added or removed,
+// since it may define the templates included by the others.
+func (w *templateWatcher) check() bool {
+	mtimes, err := w.scan()
+	if err != nil {
+		return false
+	}
+	var changed []string
+	rebuildAll := len(mtimes) != len(w.mtimes)
+	for file, t := range mtimes {
+		old, ok := w.mtimes[file]
+		if !ok {
+			rebuildAll = true
+		} else if !t.Equal(old) {
+			changed = append(changed, file)
+		}
+	}
+	w.mtimes = mtimes
+	if rebuildAll {
+		templatesLock.Lock()
+		for file := range templateErrors {
+			if _, ok := mtimes[file]; !ok {
+				delete(templateErrors, file)
+			}
+		}
+		templatesLock.Unlock()
+		BuildTemplate(w.root)
+	} else if len(changed) > 0 {
+		BuildTemplate(w.root, templateDependents(changed)...)
+	} else {
+		return false
+	}
+	w.notify()
+	return true
+}
+
+// templateDependents returns the changed files, the templates including them,
+// and the templates failing to build, which may be fixed by the change.
+func templateDependents(changed []string) []string {
+	templatesLock.RLock()
+	defer templatesLock.RUnlock()
+	files := append([]string(nil), changed...)
+	for file := range templateErrors {
+		if !utils.InSlice(file, files) {
+			files = append(files, file)
+		}
+	}
+	for file, deps := range templateDeps {
+		if utils.InSlice(file, files) {
+			continue
+		}
+		for _, dep := range deps {
+			if utils.InSlice(dep, changed) {
+				files = append(files, file)
+				break
+			}
+		}
+	}
+	return files
+}
+
+// buildErrors returns the messages of the templates failing to build by file.
+func buildErrors() map[string]string {
+	templatesLock.RLock()
+	defer templatesLock.RUnlock()
+	if len(templateErrors) == 0 {
+		return nil
+	}
+	errs := make(map[string]string, len(templateErrors))
+	for file, err := range templateErrors {
+		errs[file] = err.Error()
+	}
+	return errs
+}
+
+// notify sends the clients a "templateerror" event with the build errors,
+// or a "reload" event if the templates are built.
+func (w *templateWatcher) notify() {
+	e := &context.Event{Event: "reload", Data: "reload"}
+	if errs := buildErrors(); errs != nil {
+		e = &context.Event{Event: "templateerror", Data: errs}
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for ch := range w.clients {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (w *templateWatcher) subscribe() chan *context.Event {
+	ch := make(chan *context.Event, 1)
+	w.lock.Lock()
+	w.clients[ch] = true
+	w.lock.Unlock()
+	return ch
+}
+
+func (w *templateWatcher) unsubscribe(ch chan *context.Event) {
+	w.lock.Lock()
+	delete(w.clients, ch)
+	w.lock.Unlock()
+}
+
+// serveLiveReload streams the template events to a dev page.
+func serveLiveReload(ctx *context.Context) {
+	es, err := ctx.Output.EventStream()
+	if err != nil {
+		return
+	}
+	ch := templateWatch.subscribe()
+	defer templateWatch.unsubscribe(ch)
+	es.Serve(ch, 15*time.Second)
+}
+
+// liveReloadScript listens to the template events, it reloads the page
+// or shows the build errors in an overlay.
+const liveReloadScript = `<script>(function(){
+function overlay(errs){
+var d=document.getElementById("goweb-template-errors");
+if(d){d.parentNode.removeChild(d)}
+if(!errs){return}
+d=document.createElement("div");
+d.id="goweb-template-errors";
+d.style.cssText="position:fixed;top:0;left:0;right:0;bottom:0;z-index:2147483647;overflow:auto;padding:2em;background:rgba(0,0,0,.85);color:#f88;font:14px monospace";
+var h=document.createElement("h2");
+h.textContent="template errors";
+d.appendChild(h);
+Object.keys(errs).sort().forEach(function(f){
+var p=document.createElement("pre");
+p.style.whiteSpace="pre-wrap";
+p.textContent=f+"\n"+errs[f];
+d.appendChild(p);
+});
+document.body.appendChild(d);
+}
+var errs=%s;
+if(errs){window.addEventListener("DOMContentLoaded",function(){overlay(errs)})}
+var es=new EventSource("` + liveReloadPath + `");
+es.addEventListener("reload",function(){location.reload()});
+es.addEventListener("templateerror",function(e){overlay(JSON.parse(e.data))});
+})();</script>`
+
+// templateErrorPage returns a page showing the build errors in the overlay
+// if any of the files fails to build, or nil.
+func templateErrorPage(files []string) []byte {
+	errs := buildErrors()
+	for _, file := range files {
+		if _, ok := errs[file]; ok {
+			return injectLiveReload([]byte("<!DOCTYPE html>\n<html><body></body></html>"))
+		}
+	}
+	return nil
+}
+
+// injectLiveReload inserts the live-reload script before the </body> of the page,
+// the page without a </body>, e.g. a fragment or a non-html template, is returned unchanged.
+func injectLiveReload(page []byte) []byte {
+	i := bytes.LastIndex(page, []byte("</body>"))
+	if i < 0 {
+		return page
+	}
+	errs, _ := json.Marshal(buildErrors())
+	script := bytes.Replace([]byte(liveReloadScript), []byte("%s"), errs, 1)
+	out := make([]byte, 0, len(page)+len(script))
+	out = append(out, page[:i]...)
+	out = append(out, script...)
+	return append(out, page[i:]...)
+}
+
+// watchTemplates starts watching the views path in the dev mode,
+// and serves the live-reload events if EnableLiveReload is set.
+func watchTemplates() {
+	if templateWatch != nil {
+		templateWatch.close()
+	}
+	templateWatch = newTemplateWatcher(BConfig.WebConfig.ViewsPath)
+	go templateWatch.watch(TemplateWatchInterval)
+	if BConfig.WebConfig.EnableLiveReload {
+		Get(liveReloadPath, serveLiveReload)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2016 goweb Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goweb
+
+import (
+	"bufio"
+	"bytes"
+	gocontext "context"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	beecontext "github.com/cooleo/goweb/context"
+)
+
+// routeTimeout is the deadline of a router,
+// code is the error rendered by exception when the deadline elapses.
+type routeTimeout struct {
+	duration time.Duration
+	code     int
+}
+
+func newRouteTimeout(d time.Duration, code []int) *routeTimeout {
+	t := &routeTimeout{duration: d, code: http.StatusServiceUnavailable}
+	if len(code) > 0 {
+		t.code = code[0]
+	}
+	return t
+}
+
+// Timeout sets the deadline of the last added router.
+// when the deadline elapses the controller is abandoned and the error
+// handler of code is rendered, code is 503 by default and can be 504.
+// usage:
+//	Add("/report",&ReportController{})
+//	Timeout(2*time.Second)
+//	Get("/proxy", proxy)
+//	Timeout(time.Second, 504)
+func (p *ControllerRegister) Timeout(d time.Duration, code ...int) *ControllerRegister {
+	timeout := newRouteTimeout(d, code)
+	p.updateLast("set timeout", func(t *routerTable, r *controllerInfo) {
+		r.timeout = timeout
+	})
+	return p
+}
+
+// setTimeout sets the deadline of the routers in t which have none.
+func setTimeout(t *Tree, timeout *routeTimeout) {
+	walkTree(t, func(t *Tree) {
+		for _, l := range t.leaves {
+			if c, ok := l.runObject.(*controllerInfo); ok && c.timeout == nil {
+				c.timeout = timeout
+			}
+		}
+	})
+}
+
+// runWithTimeout runs the router in another goroutine with a copy of context,
+// whose response is buffered until the router finishes.
+// if the deadline elapses first, the request context of the router is cancelled,
+// the timeout error is rendered with context and it returns true at once,
+// the abandoned router can't write the response, and its panic is reported by reportPanic.
+// a router flushing or hijacking the response isn't buffered, and runs without the deadline since.
+func runWithTimeout(context *beecontext.Context, runner FilterFunc, timeout *routeTimeout) (timedOut bool) {
+	ctx, cancel := gocontext.WithCancel(context.Context())
+	timer := time.NewTimer(timeout.duration)
+	defer timer.Stop()
+
+	tw := &timeoutWriter{w: context.ResponseWriter, header: make(http.Header)}
+	for k, v := range context.ResponseWriter.Header() {
+		tw.header[k] = append([]string(nil), v...)
+	}
+	runContext := context.Clone(tw, context.Request.WithContext(ctx))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer cancel()
+		defer func() {
+			if err := recover(); err != nil {
+				tw.recovered(runContext, &routerPanic{err: err, stack: debug.Stack()})
+			}
+		}()
+		runner(runContext)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		<-done
+	case <-timer.C:
+		tw.mu.Lock()
+		tw.timedOut = !tw.streaming && tw.panic == nil
+		timedOut = tw.timedOut
+		tw.mu.Unlock()
+		if timedOut {
+			cancel()
+			exception(strconv.Itoa(timeout.code), context)
+			return true
+		}
+		<-done
+	}
+	tw.writeTo(context, runContext)
+	if tw.panic != nil {
+		panic(tw.panic)
+	}
+	return false
+}
+
+// routerPanic is the panic of a router running with a deadline,
+// with the stack of its goroutine.
+type routerPanic struct {
+	err   interface{}
+	stack []byte
+}
+
+// timeoutWriter buffers the response of a router running with a deadline,
+// until it's flushed or hijacked, and writes to w directly since.
+type timeoutWriter struct {
+	mu        sync.Mutex
+	w         *beecontext.Response
+	header    http.Header
+	buf       bytes.Buffer
+	code      int
+	timedOut  bool
+	streaming bool
+	panic     *routerPanic
+}
+
+// recovered keeps the panic of the router to be raised by runWithTimeout,
+// or reports it if the router is abandoned.
+func (tw *timeoutWriter) recovered(runContext *beecontext.Context, p *routerPanic) {
+	tw.mu.Lock()
+	timedOut := tw.timedOut
+	if !timedOut {
+		tw.panic = p
+	}
+	tw.mu.Unlock()
+	if !timedOut || p.err == ErrAbort {
+		return
+	}
+	if e, ok := p.err.(error); ok && appError(e) != nil {
+		return
+	}
+	reportPanic(newPanicReport(runContext, p.err, p.stack))
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.streaming {
+		return tw.w.Write(p)
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.streaming || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
+
+// Flush writes the buffered response out and flushes it,
+// the response isn't buffered since.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if !tw.streaming {
+		tw.streaming = true
+		tw.flushBuffer()
+	}
+	tw.w.Flush()
+}
+
+// Hijack hijacks the connection of the response,
+// the router isn't bound by the deadline since.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, rw, err := tw.w.Hijack()
+	if err == nil {
+		tw.streaming = true
+	}
+	return conn, rw, err
+}
+
+// flushBuffer writes the buffered header and body to w.
+func (tw *timeoutWriter) flushBuffer() {
+	header := tw.w.Header()
+	for k, v := range tw.header {
+		header[k] = v
+	}
+	if tw.code != 0 {
+		tw.w.WriteHeader(tw.code)
+	}
+	if tw.buf.Len() > 0 {
+		tw.w.Write(tw.buf.Bytes())
+		tw.buf.Reset()
+	}
+}
+
+// writeTo writes the buffered response out with context,
+// and copies the output status and data of the router.
+func (tw *timeoutWriter) writeTo(context, runContext *beecontext.Context) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.streaming {
+		tw.flushBuffer()
+	}
+	context.Output.Status = runContext.Output.Status
+	for k, v := range runContext.Input.Data() {
+		context.Input.SetData(k, v)
+	}
+}
@@ -25,6 +25,7 @@ type Statistics struct {
 	RequestURL        string
 	RequestController string
 	RequestNum        int64
+	TimeoutNum        int64
 	MinTime           time.Duration
 	MaxTime           time.Duration
 	TotalTime         time.Duration
@@ -40,11 +41,26 @@ type URLMap struct {
 // AddStatistics add statistics task.
 // it needs request method, request url, request controller and statistics time duration
 func (m *URLMap) AddStatistics(requestMethod, requestURL, requestController string, requesttime time.Duration) {
+	m.addStatistics(requestMethod, requestURL, requestController, requesttime, false)
+}
+
+// AddTimeoutStatistics add statistics task of a request which has timed out.
+// it's the same as AddStatistics, and counts the timeout too.
+func (m *URLMap) AddTimeoutStatistics(requestMethod, requestURL, requestController string, requesttime time.Duration) {
+	m.addStatistics(requestMethod, requestURL, requestController, requesttime, true)
+}
+
+func (m *URLMap) addStatistics(requestMethod, requestURL, requestController string, requesttime time.Duration, timeout bool) {
+	var timeoutNum int64
+	if timeout {
+		timeoutNum = 1
+	}
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	if method, ok := m.urlmap[requestURL]; ok {
 		if s, ok := method[requestMethod]; ok {
 			s.RequestNum++
+			s.TimeoutNum += timeoutNum
 			if s.MaxTime < requesttime {
 				s.MaxTime = requesttime
 			}
@@ -57,6 +73,7 @@ func (m *URLMap) AddStatistics(requestMethod, requestURL, requestController stri
 				RequestURL:        requestURL,
 				RequestController: requestController,
 				RequestNum:        1,
+				TimeoutNum:        timeoutNum,
 				MinTime:           requesttime,
 				MaxTime:           requesttime,
 				TotalTime:         requesttime,
@@ -73,6 +90,7 @@ func (m *URLMap) AddStatistics(requestMethod, requestURL, requestController stri
 			RequestURL:        requestURL,
 			RequestController: requestController,
 			RequestNum:        1,
+			TimeoutNum:        timeoutNum,
 			MinTime:           requesttime,
 			MaxTime:           requesttime,
 			TotalTime:         requesttime,
@@ -87,7 +105,7 @@ func (m *URLMap) GetMap() map[string]interface{} {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	var fields = []string{"requestUrl", "method", "times", "used", "max used", "min used", "avg used"}
+	var fields = []string{"requestUrl", "method", "times", "timeouts", "used", "max used", "min used", "avg used"}
 
 	var resultLists [][]string
 	content := make(map[string]interface{})
@@ -99,6 +117,7 @@ func (m *URLMap) GetMap() map[string]interface{} {
 				fmt.Sprintf("% -50s", k),
 				fmt.Sprintf("% -10s", kk),
 				fmt.Sprintf("% -16d", vv.RequestNum),
+				fmt.Sprintf("% -16d", vv.TimeoutNum),
 				fmt.Sprintf("% -16s", toS(vv.TotalTime)),
 				fmt.Sprintf("% -16s", toS(vv.MaxTime)),
 				fmt.Sprintf("% -16s", toS(vv.MinTime)),
@@ -122,6 +141,7 @@ func (m *URLMap) GetMapData() []map[string]interface{} {
 				"request_url": k,
 				"method":      kk,
 				"times":       vv.RequestNum,
+				"timeouts":    vv.TimeoutNum,
 				"total_time":  toS(vv.TotalTime),
 				"max_time":    toS(vv.MaxTime),
 				"min_time":    toS(vv.MinTime),
@@ -28,6 +28,12 @@ func TestStatics(t *testing.T) {
 	StatisticsMap.AddStatistics("POST", "/api/user/cooleo", "&admin.user", time.Duration(12000))
 	StatisticsMap.AddStatistics("POST", "/api/user/xiemengjun", "&admin.user", time.Duration(13000))
 	StatisticsMap.AddStatistics("DELETE", "/api/user", "&admin.user", time.Duration(1400))
+	StatisticsMap.AddTimeoutStatistics("GET", "/api/report", "&admin.report", time.Duration(2000000))
+	StatisticsMap.AddTimeoutStatistics("GET", "/api/report", "&admin.report", time.Duration(2000000))
+	StatisticsMap.AddStatistics("GET", "/api/report", "&admin.report", time.Duration(1000))
+	if s := StatisticsMap.urlmap["/api/report"]["GET"]; s.RequestNum != 3 || s.TimeoutNum != 2 {
+		t.Errorf("/api/report should have 3 requests and 2 timeouts, get %d and %d", s.RequestNum, s.TimeoutNum)
+	}
 	t.Log(StatisticsMap.GetMap())
 
 	data := StatisticsMap.GetMapData()
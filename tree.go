@@ -50,10 +50,10 @@ func NewTree() *Tree {
 // AddTree will add tree to the exist Tree
 // prefix should has no params
 func (t *Tree) AddTree(prefix string, tree *Tree) {
-	t.addtree(splitPath(prefix), tree, nil, "")
+	t.addtree(splitPath(prefix), tree, nil, "", patternConstraints(prefix))
 }
 
-func (t *Tree) addtree(segments []string, tree *Tree, wildcards []string, reg string) {
+func (t *Tree) addtree(segments []string, tree *Tree, wildcards []string, reg string, constraints map[string]*paramConstraint) {
 	if len(segments) == 0 {
 		panic("prefix should has path")
 	}
@@ -63,9 +63,9 @@ func (t *Tree) addtree(segments []string, tree *Tree, wildcards []string, reg st
 	if len(params) > 0 && params[0] == ":" {
 		params = params[1:]
 		if len(segments[1:]) > 0 {
-			t.addtree(segments[1:], tree, append(wildcards, params...), reg)
+			t.addtree(segments[1:], tree, append(wildcards, params...), reg, constraints)
 		} else {
-			filterTreeWithPrefix(tree, wildcards, reg)
+			filterTreeWithPrefix(tree, wildcards, reg, constraints)
 		}
 	}
 	//Rule: /login/*/access match /login/2009/11/access
@@ -107,11 +107,11 @@ func (t *Tree) addtree(segments []string, tree *Tree, wildcards []string, reg st
 				}
 			}
 			reg = strings.Trim(reg+"/"+regexpStr, "/")
-			filterTreeWithPrefix(tree, append(wildcards, params...), reg)
+			filterTreeWithPrefix(tree, append(wildcards, params...), reg, constraints)
 			t.wildcard = tree
 		} else {
 			reg = strings.Trim(reg+"/"+regexpStr, "/")
-			filterTreeWithPrefix(tree, append(wildcards, params...), reg)
+			filterTreeWithPrefix(tree, append(wildcards, params...), reg, constraints)
 			tree.prefix = seg
 			t.fixrouters = append(t.fixrouters, tree)
 		}
@@ -151,23 +151,31 @@ func (t *Tree) addtree(segments []string, tree *Tree, wildcards []string, reg st
 			}
 		}
 		reg = strings.TrimRight(strings.TrimRight(reg, "/")+"/"+regexpStr, "/")
-		t.wildcard.addtree(segments[1:], tree, append(wildcards, params...), reg)
+		t.wildcard.addtree(segments[1:], tree, append(wildcards, params...), reg, constraints)
 	} else {
 		subTree := NewTree()
 		subTree.prefix = seg
 		t.fixrouters = append(t.fixrouters, subTree)
-		subTree.addtree(segments[1:], tree, append(wildcards, params...), reg)
+		subTree.addtree(segments[1:], tree, append(wildcards, params...), reg, constraints)
 	}
 }
 
-func filterTreeWithPrefix(t *Tree, wildcards []string, reg string) {
+func filterTreeWithPrefix(t *Tree, wildcards []string, reg string, constraints map[string]*paramConstraint) {
 	for _, v := range t.fixrouters {
-		filterTreeWithPrefix(v, wildcards, reg)
+		filterTreeWithPrefix(v, wildcards, reg, constraints)
 	}
 	if t.wildcard != nil {
-		filterTreeWithPrefix(t.wildcard, wildcards, reg)
+		filterTreeWithPrefix(t.wildcard, wildcards, reg, constraints)
 	}
 	for _, l := range t.leaves {
+		for k, c := range constraints {
+			if l.constraints == nil {
+				l.constraints = make(map[string]*paramConstraint)
+			}
+			if _, ok := l.constraints[k]; !ok {
+				l.constraints[k] = c
+			}
+		}
 		if reg != "" {
 			if l.regexps != nil {
 				l.wildcards = append(wildcards, l.wildcards...)
@@ -201,24 +209,24 @@ func filterTreeWithPrefix(t *Tree, wildcards []string, reg string) {
 
 // AddRouter call addseg function
 func (t *Tree) AddRouter(pattern string, runObject interface{}) {
-	t.addseg(splitPath(pattern), runObject, nil, "")
+	t.addseg(splitPath(pattern), runObject, nil, "", patternConstraints(pattern))
 }
 
 // "/"
 // "admin" ->
-func (t *Tree) addseg(segments []string, route interface{}, wildcards []string, reg string) {
+func (t *Tree) addseg(segments []string, route interface{}, wildcards []string, reg string, constraints map[string]*paramConstraint) {
 	if len(segments) == 0 {
 		if reg != "" {
-			t.leaves = append(t.leaves, &leafInfo{runObject: route, wildcards: wildcards, regexps: regexp.MustCompile("^" + reg + "$")})
+			t.leaves = append(t.leaves, &leafInfo{runObject: route, wildcards: wildcards, regexps: regexp.MustCompile("^" + reg + "$"), constraints: constraints})
 		} else {
-			t.leaves = append(t.leaves, &leafInfo{runObject: route, wildcards: wildcards})
+			t.leaves = append(t.leaves, &leafInfo{runObject: route, wildcards: wildcards, constraints: constraints})
 		}
 	} else {
 		seg := segments[0]
 		iswild, params, regexpStr := splitSegment(seg)
 		// if it's ? meaning can igone this, so add one more rule for it
 		if len(params) > 0 && params[0] == ":" {
-			t.addseg(segments[1:], route, wildcards, reg)
+			t.addseg(segments[1:], route, wildcards, reg, constraints)
 			params = params[1:]
 		}
 		//Rule: /login/*/access match /login/2009/11/access
@@ -263,7 +271,7 @@ func (t *Tree) addseg(segments []string, route interface{}, wildcards []string,
 					params = params[1:]
 				}
 			}
-			t.wildcard.addseg(segments[1:], route, append(wildcards, params...), reg+regexpStr)
+			t.wildcard.addseg(segments[1:], route, append(wildcards, params...), reg+regexpStr, constraints)
 		} else {
 			var subTree *Tree
 			for _, sub := range t.fixrouters {
@@ -277,7 +285,7 @@ func (t *Tree) addseg(segments []string, route interface{}, wildcards []string,
 				subTree.prefix = seg
 				t.fixrouters = append(t.fixrouters, subTree)
 			}
-			subTree.addseg(segments[1:], route, wildcards, reg)
+			subTree.addseg(segments[1:], route, wildcards, reg, constraints)
 		}
 	}
 }
@@ -383,19 +391,27 @@ type leafInfo struct {
 	// if the leaf is regexp
 	regexps *regexp.Regexp
 
+	// constraints of the wildcards, eg. {":id": uuid} for the wildcard ":id:uuid"
+	constraints map[string]*paramConstraint
+
 	runObject interface{}
 }
 
 func (leaf *leafInfo) match(wildcardValues []string, ctx *context.Context) (ok bool) {
 	//fmt.Println("Leaf:", wildcardValues, leaf.wildcards, leaf.regexps)
+	var names, values []string
+	set := func(name, value string) {
+		names = append(names, name)
+		values = append(values, value)
+	}
 	if leaf.regexps == nil {
 		if len(wildcardValues) == 0 && len(leaf.wildcards) == 0 { // static path
-			return true
+			return leaf.setParams(names, values, ctx)
 		}
 		// match *
 		if len(leaf.wildcards) == 1 && leaf.wildcards[0] == ":splat" {
-			ctx.Input.SetParam(":splat", path.Join(wildcardValues...))
-			return true
+			set(":splat", path.Join(wildcardValues...))
+			return leaf.setParams(names, values, ctx)
 		}
 		// match *.* or :id
 		if len(leaf.wildcards) >= 2 && leaf.wildcards[len(leaf.wildcards)-2] == ":path" && leaf.wildcards[len(leaf.wildcards)-1] == ":ext" {
@@ -403,37 +419,37 @@ func (leaf *leafInfo) match(wildcardValues []string, ctx *context.Context) (ok b
 				lastone := wildcardValues[len(wildcardValues)-1]
 				strs := strings.SplitN(lastone, ".", 2)
 				if len(strs) == 2 {
-					ctx.Input.SetParam(":ext", strs[1])
+					set(":ext", strs[1])
 				}
-				ctx.Input.SetParam(":path", path.Join(path.Join(wildcardValues[:len(wildcardValues)-1]...), strs[0]))
-				return true
+				set(":path", path.Join(path.Join(wildcardValues[:len(wildcardValues)-1]...), strs[0]))
+				return leaf.setParams(names, values, ctx)
 			} else if len(wildcardValues) < 2 {
 				return false
 			}
 			var index int
 			for index = 0; index < len(leaf.wildcards)-2; index++ {
-				ctx.Input.SetParam(leaf.wildcards[index], wildcardValues[index])
+				set(leaf.wildcards[index], wildcardValues[index])
 			}
 			lastone := wildcardValues[len(wildcardValues)-1]
 			strs := strings.SplitN(lastone, ".", 2)
 			if len(strs) == 2 {
-				ctx.Input.SetParam(":ext", strs[1])
+				set(":ext", strs[1])
 			}
 			if index > (len(wildcardValues) - 1) {
-				ctx.Input.SetParam(":path", "")
+				set(":path", "")
 			} else {
-				ctx.Input.SetParam(":path", path.Join(path.Join(wildcardValues[index:len(wildcardValues)-1]...), strs[0]))
+				set(":path", path.Join(path.Join(wildcardValues[index:len(wildcardValues)-1]...), strs[0]))
 			}
-			return true
+			return leaf.setParams(names, values, ctx)
 		}
 		// match :id
 		if len(leaf.wildcards) != len(wildcardValues) {
 			return false
 		}
 		for j, v := range leaf.wildcards {
-			ctx.Input.SetParam(v, wildcardValues[j])
+			set(v, wildcardValues[j])
 		}
-		return true
+		return leaf.setParams(names, values, ctx)
 	}
 
 	if !leaf.regexps.MatchString(path.Join(wildcardValues...)) {
@@ -442,9 +458,23 @@ func (leaf *leafInfo) match(wildcardValues []string, ctx *context.Context) (ok b
 	matches := leaf.regexps.FindStringSubmatch(path.Join(wildcardValues...))
 	for i, match := range matches[1:] {
 		if i < len(leaf.wildcards) {
-			ctx.Input.SetParam(leaf.wildcards[i], match)
+			set(leaf.wildcards[i], match)
+		}
+	}
+	return leaf.setParams(names, values, ctx)
+}
+
+// setParams checks the param values against the leaf constraints,
+// and sets them into ctx if all of them pass.
+func (leaf *leafInfo) setParams(names, values []string, ctx *context.Context) bool {
+	for i, name := range names {
+		if c, ok := leaf.constraints[name]; ok && !c.check(values[i]) {
+			return false
 		}
 	}
+	for i, name := range names {
+		ctx.Input.SetParam(name, values[i])
+	}
 	return true
 }
 
@@ -469,6 +499,7 @@ func splitPath(key string) []string {
 // ":id([0-9]+)_:name" -> true, [:id :name], ([0-9]+)_(.+)
 // "cms_:id_:page.html" -> true, [:id_ :page], cms_(.+)(.+).html
 // "cms_:id(.+)_:page.html" -> true, [:id :page], cms_(.+)_(.+).html
+// ":id:uuid" -> true, [:id], ""         uuid is a registered constraint
 // "*" -> true, [:splat], ""
 // "*.*" -> true,[. :path :ext], ""      . meaning separator
 func splitSegment(key string) (bool, []string, string) {
@@ -494,8 +525,14 @@ func splitSegment(key string) (bool, []string, string) {
 				continue
 			}
 			if start {
-				//:id:int and :name:string
 				if v == ':' {
+					//:id:uuid, the constraint is checked when the leaf matches,
+					//it's parsed first so that :d:interval isn't taken for :d:int
+					if c, n := parseConstraint(key[i+1:]); c != nil {
+						skipnum = n
+						continue
+					}
+					//:id:int and :name:string
 					if len(key) >= i+4 {
 						if key[i+1:i+4] == "int" {
 							out = append(out, []rune("([0-9]+)")...)
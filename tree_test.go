@@ -15,8 +15,10 @@
 package goweb
 
 import (
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cooleo/goweb/context"
 )
@@ -74,6 +76,9 @@ func init() {
 	routers = append(routers, testinfo{"/v1/:v(.+)_cms/ttt_:id(.+)_:page(.+).html", "/v1/2_cms/ttt_123_1.html", map[string]string{":v": "2", ":id": "123", ":page": "1"}})
 	routers = append(routers, testinfo{"/api/projects/:pid/members/?:mid", "/api/projects/1/members", map[string]string{":pid": "1"}})
 	routers = append(routers, testinfo{"/api/projects/:pid/members/?:mid", "/api/projects/1/members/2", map[string]string{":pid": "1", ":mid": "2"}})
+	routers = append(routers, testinfo{"/v1/user/:id:uuid", "/v1/user/6ba7b810-9dad-11d1-80b4-00c04fd430c8", map[string]string{":id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}})
+	routers = append(routers, testinfo{"/v1/post/:slug:slug/:page:range[1,99]", "/v1/post/hello-world/12", map[string]string{":slug": "hello-world", ":page": "12"}})
+	routers = append(routers, testinfo{"/v1/archive/:day:date", "/v1/archive/2016-02-29", map[string]string{":day": "2016-02-29"}})
 }
 
 func TestTreeRouters(t *testing.T) {
@@ -248,6 +253,67 @@ func TestAddTree5(t *testing.T) {
 	}
 }
 
+func TestParamConstraint(t *testing.T) {
+	AddParamConstraint("even", func(value string, args []string) bool {
+		n, err := strconv.Atoi(value)
+		return err == nil && n%2 == 0
+	})
+	AddParamConstraint("interval", func(value string, args []string) bool {
+		_, err := time.ParseDuration(value)
+		return err == nil
+	})
+	tr := NewTree()
+	tr.AddRouter("/user/:id:uuid", "user")
+	tr.AddRouter("/user/:name", "username")
+	tr.AddRouter("/code/:code:len[2,3]", "code")
+	tr.AddRouter("/sort/:order:enum[asc,desc]", "sort")
+	tr.AddRouter("/page/:page:range[1,10]", "page")
+	tr.AddRouter("/day/:day:date", "day")
+	tr.AddRouter("/num/:num:even", "num")
+	tr.AddRouter("/every/:every:interval", "every")
+	tests := []struct {
+		url string
+		obj interface{}
+	}{
+		{"/user/6ba7b810-9dad-11d1-80b4-00c04fd430c8", "user"},
+		{"/user/cooleo", "username"},
+		{"/code/ab", "code"},
+		{"/code/abcd", nil},
+		{"/sort/asc", "sort"},
+		{"/sort/up", nil},
+		{"/page/10", "page"},
+		{"/page/11", nil},
+		{"/page/abc", nil},
+		{"/day/2016-02-29", "day"},
+		{"/day/2015-02-29", nil},
+		{"/num/42", "num"},
+		{"/num/43", nil},
+		{"/every/1m30s", "every"},
+		{"/every/90", nil},
+	}
+	for _, test := range tests {
+		ctx := context.NewContext()
+		if obj := tr.Match(test.url, ctx); obj != test.obj {
+			t.Errorf("%s: expect %v, got %v", test.url, test.obj, obj)
+		}
+	}
+	ctx := context.NewContext()
+	tr.Match("/user/cooleo", ctx)
+	if ctx.Input.Param(":id") != "" || ctx.Input.Param(":name") != "cooleo" {
+		t.Fatal("params of the rejected route should not be set")
+	}
+
+	t1 := NewTree()
+	t1.AddTree("/v1/:shopid:alpha", tr)
+	ctx = context.NewContext()
+	if obj := t1.Match("/v1/shop/page/2", ctx); obj != "page" || ctx.Input.Param(":shopid") != "shop" {
+		t.Fatal("/v1/:shopid:alpha/page/:page:range[1,10] can't get obj")
+	}
+	if obj := t1.Match("/v1/shop1/page/2", context.NewContext()); obj != nil {
+		t.Fatal("/v1/shop1/page/2 should not match the alpha constraint")
+	}
+}
+
 func TestSplitPath(t *testing.T) {
 	a := splitPath("")
 	if len(a) != 0 {